@@ -5,8 +5,10 @@ go 1.21
 require (
 	github.com/campoy/embedmd v1.0.0
 	github.com/nlpodyssey/gopickle v0.3.0
-	golang.org/x/text v0.14.0
 	gonum.org/v1/gonum v0.14.0
 )
 
-require github.com/pmezard/go-difflib v1.0.0 // indirect
+require (
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
@@ -6,6 +6,7 @@ package npy
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	py "github.com/nlpodyssey/gopickle/types"
@@ -17,6 +18,7 @@ type Array struct {
 	shape   []int
 	strides []int
 	fortran bool
+	offset  int // offset in bytes of the first element, for views.
 
 	data any
 }
@@ -39,6 +41,110 @@ func (*Array) PyNew(args ...any) (any, error) {
 	return newArray(subtype, *descr, shape, strides, data, flags)
 }
 
+// ArrayOption configures the creation of an Array.
+type ArrayOption func(*arrayConfig)
+
+type arrayConfig struct {
+	fortran bool
+	strides []int
+}
+
+// WithFortranOrder specifies whether the data of the array is laid out in
+// Fortran-order (column-major) instead of C-order (row-major.)
+func WithFortranOrder(fortran bool) ArrayOption {
+	return func(cfg *arrayConfig) {
+		cfg.fortran = fortran
+	}
+}
+
+// WithStrides specifies the strides, in bytes, used to access the data of
+// the array.
+func WithStrides(strides ...int) ArrayOption {
+	return func(cfg *arrayConfig) {
+		cfg.strides = append([]int(nil), strides...)
+	}
+}
+
+// NewArray creates a new array with the provided data type, shape and data.
+//
+// The data must be a slice of the Go type corresponding to the data type
+// (e.g. []float64 for "<f8", []string for "|S4" or "<U10", []any for "|O".)
+// A scalar value may be used as data for 0-dimensional arrays.
+//
+// By default, the data is laid out in C-order (row-major.)
+// The returned array shares the storage of the provided data slice.
+func NewArray(descr ArrayDescr, shape []int, data any, opts ...ArrayOption) (*Array, error) {
+	var cfg arrayConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	rt, err := descr.goType()
+	if err != nil {
+		return nil, err
+	}
+	for i, dim := range shape {
+		if dim < 0 {
+			return nil, fmt.Errorf("npy: invalid shape[%d]=%d: %w", i, dim, ErrShapeMismatch)
+		}
+	}
+
+	rv := reflect.ValueOf(data)
+	switch {
+	case descr.kind == 'O' && rv.IsValid() && rv.Type() == reflect.TypeOf((*py.List)(nil)):
+		// ok.
+	case rv.IsValid() && rv.Kind() == reflect.Slice && rv.Type().Elem() == rt:
+		// ok.
+	case len(shape) == 0 && (rt == anyType || (rv.IsValid() && rv.Type() == rt)):
+		sli := reflect.MakeSlice(reflect.SliceOf(rt), 1, 1)
+		if rv.IsValid() {
+			sli.Index(0).Set(rv)
+		}
+		data = sli.Interface()
+	default:
+		return nil, fmt.Errorf("npy: invalid data type %T for dtype %q: %w", data, descr.Descr(), ErrTypeMismatch)
+	}
+
+	arr := &Array{
+		descr:   descr,
+		shape:   shape,
+		fortran: cfg.fortran,
+		data:    data,
+	}
+
+	switch cfg.strides {
+	case nil:
+		err = arr.setupStrides()
+		if err != nil {
+			return nil, fmt.Errorf("npy: could not setup strides: %w", err)
+		}
+	default:
+		if len(cfg.strides) != len(shape) {
+			return nil, fmt.Errorf("npy: invalid number of strides (got=%d, want=%d): %w", len(cfg.strides), len(shape), ErrShapeMismatch)
+		}
+		for i, stride := range cfg.strides {
+			if stride < 0 || (descr.esize > 0 && stride%descr.esize != 0) {
+				return nil, fmt.Errorf("npy: invalid stride[%d]=%d for item size %d: %w", i, stride, descr.esize, ErrShapeMismatch)
+			}
+		}
+		arr.strides = cfg.strides
+	}
+
+	// make sure all elements are addressable.
+	n := arr.values().Len()
+	if numElems(shape) > 0 {
+		last := 0
+		for i, dim := range shape {
+			last += (dim - 1) * arr.strides[i]
+		}
+		if arr.elem(last) >= n {
+			return nil, fmt.Errorf("npy: data too short (len=%d) for shape %v: %w", n, shape, ErrShapeMismatch)
+		}
+	}
+
+	return arr, nil
+}
+
 func newArray(subtype any, descr ArrayDescr, shape, strides []int, data []byte, flags int) (*Array, error) {
 	switch subtype := subtype.(type) {
 	case *Array:
@@ -198,6 +304,309 @@ func (arr Array) Data() any {
 	return arr.data
 }
 
+// At returns the element of the array at the provided multi-dimensional
+// index.
+// At panics if the number of indices does not match the number of
+// dimensions of the array, or if an index is out of range.
+func (arr Array) At(idx ...int) any {
+	if len(arr.shape) == 0 && len(idx) == 0 {
+		if rv := arr.values(); rv.IsValid() {
+			return rv.Index(arr.elem(arr.offset)).Interface()
+		}
+		return arr.data
+	}
+	return arr.values().Index(arr.index(idx)).Interface()
+}
+
+// Set sets the element of the array at the provided multi-dimensional
+// index to v.
+// Set panics if the number of indices does not match the number of
+// dimensions of the array, if an index is out of range, or if v can not
+// be assigned to an element of the array.
+//
+// Set modifies the underlying storage, which is shared by all the views
+// of an array.
+func (arr *Array) Set(v any, idx ...int) {
+	rv := arr.values()
+	if len(arr.shape) == 0 && len(idx) == 0 && !rv.IsValid() {
+		if v != nil && arr.data != nil && reflect.TypeOf(v) != reflect.TypeOf(arr.data) {
+			panic(fmt.Errorf("npy: can not assign %T to %T: %w", v, arr.data, ErrTypeMismatch))
+		}
+		arr.data = v
+		return
+	}
+
+	i := arr.elem(arr.offset)
+	if len(arr.shape) > 0 || len(idx) > 0 {
+		i = arr.index(idx)
+	}
+
+	elem := rv.Index(i)
+	switch {
+	case v == nil && elem.Kind() == reflect.Interface:
+		elem.Set(reflect.Zero(elem.Type()))
+	case v != nil && reflect.TypeOf(v).AssignableTo(elem.Type()):
+		elem.Set(reflect.ValueOf(v))
+	default:
+		panic(fmt.Errorf("npy: can not assign %T to %v: %w", v, elem.Type(), ErrTypeMismatch))
+	}
+}
+
+// index returns the index into the underlying data slice of the element
+// at the provided multi-dimensional index.
+func (arr Array) index(idx []int) int {
+	if len(idx) != len(arr.shape) {
+		panic(fmt.Errorf("npy: invalid number of indices (got=%d, want=%d)", len(idx), len(arr.shape)))
+	}
+	offset := arr.offset
+	for i, v := range idx {
+		if v < 0 || v >= arr.shape[i] {
+			panic(fmt.Errorf("npy: index %d out of range for axis %d with size %d", v, i, arr.shape[i]))
+		}
+		offset += v * arr.strides[i]
+	}
+	return arr.elem(offset)
+}
+
+// elem returns the index into the underlying data slice of the element
+// at the provided offset, in bytes.
+func (arr Array) elem(offset int) int {
+	if arr.descr.esize == 0 {
+		// zero-sized elements (e.g. '|S0', '|V0') hold no data: they all
+		// map to the first element of the data slice.
+		return 0
+	}
+	return offset / arr.descr.esize
+}
+
+// values returns the underlying data slice, or an invalid reflect.Value
+// if the data is not held in a slice.
+func (arr Array) values() reflect.Value {
+	switch data := arr.data.(type) {
+	case *py.List:
+		if data == nil {
+			return reflect.Value{}
+		}
+		return reflect.ValueOf([]any(*data))
+	}
+	rv := reflect.ValueOf(arr.data)
+	if rv.Kind() != reflect.Slice {
+		return reflect.Value{}
+	}
+	return rv
+}
+
+// Reshape returns an array with the same data and the new provided shape.
+// One of the dimensions may be -1, in which case its value is inferred
+// from the size of the array and the other dimensions.
+//
+// Reshape returns a view sharing the storage of the original array if
+// the array is C-contiguous, and a C-contiguous copy otherwise.
+func (arr Array) Reshape(shape ...int) (*Array, error) {
+	var (
+		size  = numElems(arr.shape)
+		n     = 1
+		infer = -1
+	)
+	shape = append([]int(nil), shape...)
+	for i, dim := range shape {
+		switch {
+		case dim == -1 && infer < 0:
+			infer = i
+		case dim < 0:
+			return nil, fmt.Errorf("npy: invalid reshape dimension %d: %w", dim, ErrShapeMismatch)
+		default:
+			n *= dim
+		}
+	}
+	if infer >= 0 {
+		if n == 0 || size%n != 0 {
+			return nil, fmt.Errorf("npy: can not reshape array of size %d into shape %v: %w", size, shape, ErrShapeMismatch)
+		}
+		shape[infer] = size / n
+		n *= shape[infer]
+	}
+	if n != size {
+		return nil, fmt.Errorf("npy: can not reshape array of size %d into shape %v: %w", size, shape, ErrShapeMismatch)
+	}
+
+	src := &arr
+	if !arr.isCContiguous() {
+		src = arr.Contiguous()
+	}
+
+	o := &Array{
+		descr:  src.descr,
+		shape:  shape,
+		offset: src.offset,
+		data:   src.data,
+	}
+	err := o.setupStrides()
+	if err != nil {
+		return nil, fmt.Errorf("npy: could not setup strides: %w", err)
+	}
+	return o, nil
+}
+
+// Transpose returns a view of the array with its axes permuted.
+// If no axes are provided, the order of the axes is reversed.
+//
+// The returned array shares the storage of the original array.
+func (arr Array) Transpose(axes ...int) (*Array, error) {
+	ndim := len(arr.shape)
+	if len(axes) == 0 {
+		axes = make([]int, ndim)
+		for i := range axes {
+			axes[i] = ndim - 1 - i
+		}
+	}
+	if len(axes) != ndim {
+		return nil, fmt.Errorf("npy: axes don't match array (got=%d, want=%d): %w", len(axes), ndim, ErrShapeMismatch)
+	}
+
+	var (
+		seen    = make([]bool, ndim)
+		shape   = make([]int, ndim)
+		strides = make([]int, ndim)
+	)
+	for i, axis := range axes {
+		if axis < 0 || axis >= ndim || seen[axis] {
+			return nil, fmt.Errorf("npy: invalid transpose axes %v: %w", axes, ErrShapeMismatch)
+		}
+		seen[axis] = true
+		shape[i] = arr.shape[axis]
+		strides[i] = arr.strides[axis]
+	}
+
+	o := &Array{
+		descr:   arr.descr,
+		shape:   shape,
+		strides: strides,
+		offset:  arr.offset,
+		data:    arr.data,
+	}
+	o.fortran = !o.isCContiguous() && o.isFContiguous()
+	return o, nil
+}
+
+// Slice returns a view of the array, restricted to the [start, stop)
+// range of indices along the provided axis.
+//
+// The returned array shares the storage of the original array.
+// Use Contiguous to create a compact copy of the view.
+func (arr Array) Slice(axis, start, stop int) (*Array, error) {
+	if axis < 0 || axis >= len(arr.shape) {
+		return nil, fmt.Errorf("npy: axis %d out of range for array with %d dimensions: %w", axis, len(arr.shape), ErrShapeMismatch)
+	}
+	if start < 0 || stop < start || stop > arr.shape[axis] {
+		return nil, fmt.Errorf("npy: invalid slice [%d:%d] for axis %d with size %d: %w", start, stop, axis, arr.shape[axis], ErrShapeMismatch)
+	}
+
+	o := &Array{
+		descr:   arr.descr,
+		shape:   append([]int(nil), arr.shape...),
+		strides: append([]int(nil), arr.strides...),
+		offset:  arr.offset + start*arr.strides[axis],
+		data:    arr.data,
+	}
+	o.shape[axis] = stop - start
+	o.fortran = !o.isCContiguous() && o.isFContiguous()
+	return o, nil
+}
+
+// Contiguous returns a C-contiguous copy of the array.
+func (arr Array) Contiguous() *Array {
+	o := &Array{
+		descr: arr.descr,
+		shape: append([]int(nil), arr.shape...),
+	}
+	if len(arr.shape) == 0 {
+		o.shape = arr.shape
+	}
+
+	src := arr.values()
+	if !src.IsValid() {
+		o.data = arr.data
+		_ = o.setupStrides()
+		return o
+	}
+
+	n := numElems(arr.shape)
+	dst := reflect.MakeSlice(reflect.SliceOf(src.Type().Elem()), n, n)
+	arr.each(func(i, j int) {
+		dst.Index(i).Set(src.Index(j))
+	})
+
+	switch arr.data.(type) {
+	case *py.List:
+		lst := py.List(dst.Interface().([]any))
+		o.data = &lst
+	default:
+		o.data = dst.Convert(src.Type()).Interface()
+	}
+	_ = o.setupStrides()
+	return o
+}
+
+// each calls f for each element of the array, in C-order, with the
+// element's position in C-order and its index in the underlying data slice.
+func (arr Array) each(f func(i, j int)) {
+	n := numElems(arr.shape)
+	if n == 0 {
+		return
+	}
+	var (
+		ndim = len(arr.shape)
+		idx  = make([]int, ndim)
+	)
+	offset := arr.offset
+	for i := 0; i < n; i++ {
+		f(i, arr.elem(offset))
+		for k := ndim - 1; k >= 0; k-- {
+			idx[k]++
+			offset += arr.strides[k]
+			if idx[k] < arr.shape[k] {
+				break
+			}
+			offset -= idx[k] * arr.strides[k]
+			idx[k] = 0
+		}
+	}
+}
+
+// isCompact returns whether the array data holds exactly the elements
+// of the array, starting at the first element of the data.
+func (arr Array) isCompact() bool {
+	if arr.offset != 0 {
+		return false
+	}
+	rv := arr.values()
+	return !rv.IsValid() || rv.Len() == numElems(arr.shape)
+}
+
+func (arr Array) isCContiguous() bool {
+	return isContiguous(arr.shape, arr.strides, arr.descr.esize, false)
+}
+
+func (arr Array) isFContiguous() bool {
+	return isContiguous(arr.shape, arr.strides, arr.descr.esize, true)
+}
+
+func isContiguous(shape, strides []int, itemsize int, fortran bool) bool {
+	stride := itemsize
+	for k := range shape {
+		i := k
+		if !fortran {
+			i = len(shape) - 1 - k
+		}
+		if shape[i] != 1 && strides[i] != stride {
+			return false
+		}
+		stride *= shape[i]
+	}
+	return true
+}
+
 func (arr Array) String() string {
 	o := new(strings.Builder)
 	fmt.Fprintf(o, "Array{descr: %v, ", arr.descr)
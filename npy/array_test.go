@@ -5,10 +5,13 @@
 package npy
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 	"testing"
+
+	py "github.com/nlpodyssey/gopickle/types"
 )
 
 func TestArrayStringer(t *testing.T) {
@@ -49,3 +52,473 @@ func TestArrayStringer(t *testing.T) {
 		t.Fatalf("invalid fortran:\ngot= %+v\nwant=%+v", got, want)
 	}
 }
+
+func loadArray(t *testing.T, fname string) *Array {
+	t.Helper()
+
+	f, err := os.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open testdata: %+v", err)
+	}
+	defer f.Close()
+
+	var arr Array
+	err = Read(f, &arr)
+	if err != nil {
+		t.Fatalf("could not read data: %+v", err)
+	}
+	return &arr
+}
+
+func TestArrayAt(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+	for _, tc := range []struct {
+		idx  []int
+		want float64
+	}{
+		{[]int{0, 0, 0}, 0},
+		{[]int{0, 0, 3}, 3},
+		{[]int{0, 2, 1}, 9},
+		{[]int{1, 0, 0}, 12},
+		{[]int{1, 2, 3}, 23},
+	} {
+		if got, want := arr.At(tc.idx...), tc.want; got != want {
+			t.Fatalf("invalid value at %v: got=%v, want=%v", tc.idx, got, want)
+		}
+	}
+
+	farr := loadArray(t, "../testdata/data_float32_2x3_forder.npy")
+	for _, tc := range []struct {
+		idx  []int
+		want float32
+	}{
+		{[]int{0, 0}, 0},
+		{[]int{1, 0}, 1},
+		{[]int{0, 1}, 2},
+		{[]int{1, 2}, 5},
+	} {
+		if got, want := farr.At(tc.idx...), tc.want; got != want {
+			t.Fatalf("invalid value at %v: got=%v, want=%v", tc.idx, got, want)
+		}
+	}
+
+	sarr := loadArray(t, "../testdata/data_float64_scalar_corder.npy")
+	if got, want := sarr.At(), float64(42); got != want {
+		t.Fatalf("invalid scalar value: got=%v, want=%v", got, want)
+	}
+
+	for _, tc := range []struct {
+		dtype string
+		data  any
+		zero  any
+	}{
+		{"|S0", []string{"", "", ""}, ""},
+		{"|V0", [][]byte{{}, {}, {}}, []byte{}},
+	} {
+		descr, err := ParseDescr(tc.dtype)
+		if err != nil {
+			t.Fatalf("could not parse descr %q: %+v", tc.dtype, err)
+		}
+		zarr, err := NewArray(descr, []int{3}, tc.data)
+		if err != nil {
+			t.Fatalf("could not create %q array: %+v", tc.dtype, err)
+		}
+		if got, want := zarr.At(2), tc.zero; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid %q value: got=%v, want=%v", tc.dtype, got, want)
+		}
+		zarr.Set(tc.zero, 1)
+		if got, want := zarr.Contiguous().Data(), tc.data; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid %q data:\ngot= %v\nwant=%v", tc.dtype, got, want)
+		}
+	}
+
+	for _, idx := range [][]int{
+		{},
+		{0, 0},
+		{2, 0, 0},
+		{0, -1, 0},
+		{0, 0, 4},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected a panic for index %v", idx)
+				}
+			}()
+			_ = arr.At(idx...)
+		}()
+	}
+}
+
+func TestArraySet(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+	view, err := arr.Slice(1, 1, 3)
+	if err != nil {
+		t.Fatalf("could not slice array: %+v", err)
+	}
+
+	view.Set(-1.0, 1, 0, 2)
+	if got, want := view.At(1, 0, 2), -1.0; got != want {
+		t.Fatalf("invalid view value: got=%v, want=%v", got, want)
+	}
+	if got, want := arr.At(1, 1, 2), -1.0; got != want {
+		t.Fatalf("invalid shared value: got=%v, want=%v", got, want)
+	}
+
+	func() {
+		defer func() {
+			err, ok := recover().(error)
+			if !ok || !errors.Is(err, ErrTypeMismatch) {
+				t.Fatalf("expected a type mismatch panic, got=%v", err)
+			}
+		}()
+		arr.Set(int64(1), 0, 0, 0)
+	}()
+
+	obj := &Array{
+		descr:   ArrayDescr{kind: 'O', esize: 8, align: 8, flags: 63},
+		shape:   []int{2},
+		strides: []int{8},
+		data:    py.NewList(),
+	}
+	obj.data.(*py.List).Append("a")
+	obj.data.(*py.List).Append(int64(2))
+	obj.Set(nil, 0)
+	obj.Set([]any{1.5}, 1)
+	if got, want := obj.data, (&py.List{nil, []any{1.5}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid object array:\ngot= %v\nwant=%v", got, want)
+	}
+}
+
+func TestArrayReshape(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+
+	for _, tc := range []struct {
+		shape []int
+		want  []int
+		at    []int
+		val   float64
+	}{
+		{[]int{6, 4}, []int{6, 4}, []int{5, 1}, 21},
+		{[]int{4, -1}, []int{4, 6}, []int{2, 3}, 15},
+		{[]int{24}, []int{24}, []int{17}, 17},
+		{[]int{2, 2, 2, 3}, []int{2, 2, 2, 3}, []int{1, 0, 1, 2}, 17},
+	} {
+		o, err := arr.Reshape(tc.shape...)
+		if err != nil {
+			t.Fatalf("could not reshape to %v: %+v", tc.shape, err)
+		}
+		if got, want := o.Shape(), tc.want; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+		if got, want := o.At(tc.at...), tc.val; got != want {
+			t.Fatalf("invalid value at %v: got=%v, want=%v", tc.at, got, want)
+		}
+	}
+
+	for _, shape := range [][]int{
+		{5, 5},
+		{-1, -1},
+		{5, -1},
+		{0, -1},
+		{-2, 12},
+	} {
+		_, err := arr.Reshape(shape...)
+		if err == nil {
+			t.Fatalf("expected an error reshaping to %v", shape)
+		}
+	}
+
+	// reshaping a non-contiguous view creates a copy.
+	tr, err := arr.Transpose()
+	if err != nil {
+		t.Fatalf("could not transpose: %+v", err)
+	}
+	flat, err := tr.Reshape(-1)
+	if err != nil {
+		t.Fatalf("could not reshape: %+v", err)
+	}
+	if got, want := flat.Data().([]float64)[:6], []float64{0, 12, 4, 16, 8, 20}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+}
+
+func TestArrayTranspose(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+
+	tr, err := arr.Transpose()
+	if err != nil {
+		t.Fatalf("could not transpose: %+v", err)
+	}
+	if got, want := tr.Shape(), []int{4, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := tr.Strides(), []int{8, 32, 96}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := tr.Fortran(), true; got != want {
+		t.Fatalf("invalid fortran: got=%v, want=%v", got, want)
+	}
+	if got, want := tr.At(3, 1, 0), 7.0; got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+
+	tr, err = arr.Transpose(1, 0, 2)
+	if err != nil {
+		t.Fatalf("could not transpose: %+v", err)
+	}
+	if got, want := tr.Shape(), []int{3, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := tr.At(2, 1, 3), 23.0; got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+	if got, want := tr.Fortran(), false; got != want {
+		t.Fatalf("invalid fortran: got=%v, want=%v", got, want)
+	}
+
+	for _, axes := range [][]int{
+		{0, 1},
+		{0, 1, 1},
+		{0, 1, 3},
+		{-1, 0, 1},
+	} {
+		_, err := arr.Transpose(axes...)
+		if err == nil {
+			t.Fatalf("expected an error for axes %v", axes)
+		}
+	}
+}
+
+func TestArraySlice(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+
+	v1, err := arr.Slice(2, 1, 3)
+	if err != nil {
+		t.Fatalf("could not slice: %+v", err)
+	}
+	v2, err := v1.Slice(0, 1, 2)
+	if err != nil {
+		t.Fatalf("could not slice: %+v", err)
+	}
+	if got, want := v2.Shape(), []int{1, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := v2.Strides(), arr.Strides(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := v2.At(0, 2, 0), 21.0; got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+
+	farr := loadArray(t, "../testdata/data_float32_2x3_forder.npy")
+	for _, tc := range []struct {
+		axis, start, stop int
+		fortran           bool
+	}{
+		{1, 1, 3, true},
+		{0, 0, 1, false},
+		{0, 1, 2, false},
+	} {
+		v, err := farr.Slice(tc.axis, tc.start, tc.stop)
+		if err != nil {
+			t.Fatalf("could not slice: %+v", err)
+		}
+		if got, want := v.Fortran(), tc.fortran; got != want {
+			t.Fatalf("invalid fortran flag for slice %+v: got=%v, want=%v", tc, got, want)
+		}
+	}
+
+	empty, err := arr.Slice(1, 2, 2)
+	if err != nil {
+		t.Fatalf("could not slice: %+v", err)
+	}
+	if got, want := empty.Contiguous().Data(), []float64{}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid empty data:\ngot= %v\nwant=%v", got, want)
+	}
+
+	for _, tc := range []struct {
+		axis, start, stop int
+	}{
+		{-1, 0, 1},
+		{3, 0, 1},
+		{0, -1, 1},
+		{0, 1, 0},
+		{0, 0, 3},
+	} {
+		_, err := arr.Slice(tc.axis, tc.start, tc.stop)
+		if err == nil {
+			t.Fatalf("expected an error for slice %+v", tc)
+		}
+	}
+}
+
+func TestArrayContiguous(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float64_2x3x4_corder.npy")
+
+	view, err := arr.Slice(1, 1, 2)
+	if err != nil {
+		t.Fatalf("could not slice: %+v", err)
+	}
+	view, err = view.Transpose()
+	if err != nil {
+		t.Fatalf("could not transpose: %+v", err)
+	}
+
+	c := view.Contiguous()
+	if got, want := c.Shape(), []int{4, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := c.Strides(), []int{16, 16, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := c.Data(), []float64{4, 16, 5, 17, 6, 18, 7, 19}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+
+	c.Set(-1.0, 0, 0, 0)
+	if got, want := arr.At(0, 1, 0), 4.0; got != want {
+		t.Fatalf("contiguous copy shares storage: got=%v, want=%v", got, want)
+	}
+
+	farr := loadArray(t, "../testdata/data_float32_2x3_forder.npy")
+	if got, want := farr.Contiguous().Data(), []float32{0, 2, 4, 1, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+}
+
+func TestNewArray(t *testing.T) {
+	f8, err := ParseDescr("<f8")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+	u10, err := ParseDescr("<U10")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+	obj, err := ParseDescr("|O")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		descr   ArrayDescr
+		shape   []int
+		data    any
+		opts    []ArrayOption
+		strides []int
+		at      []int
+		want    any
+		err     error
+	}{
+		{
+			name:    "f8-c-order",
+			descr:   f8,
+			shape:   []int{2, 3},
+			data:    []float64{0, 1, 2, 3, 4, 5},
+			strides: []int{24, 8},
+			at:      []int{1, 0},
+			want:    3.0,
+		},
+		{
+			name:    "f8-f-order",
+			descr:   f8,
+			shape:   []int{2, 3},
+			data:    []float64{0, 1, 2, 3, 4, 5},
+			opts:    []ArrayOption{WithFortranOrder(true)},
+			strides: []int{8, 16},
+			at:      []int{1, 0},
+			want:    1.0,
+		},
+		{
+			name:    "f8-strides",
+			descr:   f8,
+			shape:   []int{2, 2},
+			data:    []float64{0, 1, 2, 3, 4, 5},
+			opts:    []ArrayOption{WithStrides(24, 16)},
+			strides: []int{24, 16},
+			at:      []int{1, 1},
+			want:    5.0,
+		},
+		{
+			name:  "f8-scalar",
+			descr: f8,
+			data:  42.0,
+			want:  42.0,
+		},
+		{
+			name:    "U10",
+			descr:   u10,
+			shape:   []int{2},
+			data:    []string{"hello", "world"},
+			strides: []int{40},
+			at:      []int{1},
+			want:    "world",
+		},
+		{
+			name:    "object",
+			descr:   obj,
+			shape:   []int{2},
+			data:    []any{"hello", 42},
+			strides: []int{8},
+			at:      []int{1},
+			want:    42,
+		},
+		{
+			name:  "invalid-type",
+			descr: f8,
+			shape: []int{2},
+			data:  []float32{1, 2},
+			err:   ErrTypeMismatch,
+		},
+		{
+			name:  "invalid-scalar",
+			descr: f8,
+			data:  float32(42),
+			err:   ErrTypeMismatch,
+		},
+		{
+			name:  "too-short",
+			descr: f8,
+			shape: []int{2, 3},
+			data:  []float64{0, 1, 2, 3, 4},
+			err:   ErrShapeMismatch,
+		},
+		{
+			name:  "invalid-strides",
+			descr: f8,
+			shape: []int{2, 3},
+			data:  []float64{0, 1, 2, 3, 4, 5},
+			opts:  []ArrayOption{WithStrides(24, 4)},
+			err:   ErrShapeMismatch,
+		},
+		{
+			name:  "invalid-shape",
+			descr: f8,
+			shape: []int{-1},
+			data:  []float64{0},
+			err:   ErrShapeMismatch,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			arr, err := NewArray(tc.descr, tc.shape, tc.data, tc.opts...)
+			switch {
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", err, tc.err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("could not create array: %+v", err)
+			}
+
+			if got, want := arr.Strides(), tc.strides; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+			}
+			if got, want := arr.At(tc.at...), tc.want; got != want {
+				t.Fatalf("invalid value at %v: got=%v, want=%v", tc.at, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ctxChunk is the maximum number of bytes read or written between two
+// checks of the context cancellation.
+const ctxChunk = 64 << 10
+
+// ReadContext reads the numpy-array data from the underlying NumPy file,
+// like Read.
+// ReadContext checks for the cancellation of the provided context while
+// reading the array data.
+// If the context is canceled, ReadContext returns the context error,
+// wrapped with the number of elements that were read.
+func (r *Reader) ReadContext(ctx context.Context, ptr interface{}) error {
+	cr := &ctxReader{ctx: ctx, r: r.r}
+	r.r = cr
+	defer func() {
+		r.r = cr.r
+	}()
+
+	err := r.Read(ptr)
+	if cr.err != nil {
+		return fmt.Errorf("npy: read canceled after %s: %w", processed(r.Header, cr.n), cr.err)
+	}
+	return err
+}
+
+// WriteContext writes 'val' into 'w' in the NumPy data format, like Write.
+// WriteContext checks for the cancellation of the provided context while
+// writing the array data.
+// If the context is canceled, WriteContext returns the context error,
+// wrapped with the number of elements that were written.
+func WriteContext(ctx context.Context, w io.Writer, val interface{}, opts ...WriteOption) error {
+	cw := &ctxWriter{ctx: ctx, w: w}
+	err := Write(cw, val, opts...)
+	if cw.err != nil {
+		return fmt.Errorf("npy: write canceled after %s: %w", cw.processed(), cw.err)
+	}
+	return err
+}
+
+// processed describes how much of the array data has been processed,
+// given the number n of bytes of array data read or written.
+func processed(hdr Header, n int64) string {
+	dt, err := hdr.Dtype()
+	if err != nil || dt.kind == 'O' || dt.esize <= 0 {
+		// the data of object arrays is pickled: no element boundaries.
+		return fmt.Sprintf("%d bytes", n)
+	}
+	return fmt.Sprintf("%d elements", n/int64(dt.esize))
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64 // number of bytes read.
+	err error // context error, if any.
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ctx.Done():
+		r.err = r.ctx.Err()
+		return 0, r.err
+	default:
+	}
+
+	if len(p) > ctxChunk {
+		p = p[:ctxChunk]
+	}
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+	n   int64   // number of bytes written.
+	hdr *Header // header of the NumPy data file, once written.
+	err error   // context error, if any.
+}
+
+func (w *ctxWriter) Write(p []byte) (int, error) {
+	nn := 0
+	for len(p) > 0 {
+		select {
+		case <-w.ctx.Done():
+			w.err = w.ctx.Err()
+			return nn, w.err
+		default:
+		}
+
+		chunk := p
+		if len(chunk) > ctxChunk {
+			chunk = chunk[:ctxChunk]
+		}
+
+		n, err := w.w.Write(chunk)
+		nn += n
+		w.n += int64(n)
+		if err != nil {
+			return nn, err
+		}
+		p = p[n:]
+	}
+	return nn, nil
+}
+
+func (w *ctxWriter) header(hdr Header) {
+	w.hdr = &hdr
+}
+
+// processed describes how much of the array data has been written.
+func (w *ctxWriter) processed() string {
+	if w.hdr == nil || w.n < w.hdr.DataOffset {
+		return "0 elements"
+	}
+	return processed(*w.hdr, w.n-w.hdr.DataOffset)
+}
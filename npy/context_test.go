@@ -0,0 +1,199 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// cancelWriter cancels a context once more than n bytes have been written.
+type cancelWriter struct {
+	w      io.Writer
+	n      int
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n -= n
+	if w.n < 0 {
+		w.cancel()
+	}
+	return n, err
+}
+
+// cancelReader cancels a context once more than n bytes have been read.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n -= n
+	if r.n < 0 {
+		r.cancel()
+	}
+	return n, err
+}
+
+func TestWriteContext(t *testing.T) {
+	data := make([]float64, 100)
+	for i := range data {
+		data[i] = float64(i)
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := WriteContext(context.Background(), buf, data)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+
+		var got []float64
+		err = Read(buf, &got)
+		if err != nil {
+			t.Fatalf("could not read back data: %+v", err)
+		}
+		if !reflect.DeepEqual(got, data) {
+			t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", got, data)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		buf := new(bytes.Buffer)
+		err := Write(buf, data)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		hdr := buf.Len() - len(data)*8
+
+		// cancel once the header and 10 elements have been written.
+		w := &cancelWriter{w: io.Discard, n: hdr + 10*8 - 1, cancel: cancel}
+		err = WriteContext(ctx, w, data)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+		}
+		if got, want := err.Error(), "after 10 elements"; !strings.Contains(got, want) {
+			t.Fatalf("invalid error message: got=%q, want=%q", got, want)
+		}
+	})
+
+	t.Run("canceled-large-header", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// a header larger than the chunks written between two checks
+		// of the context cancellation.
+		shape := make([]int, ctxChunk/2)
+		for i := range shape {
+			shape[i] = 1
+		}
+		shape[len(shape)-1] = len(data)
+		tensor, err := NewTensor(shape, data)
+		if err != nil {
+			t.Fatalf("could not create tensor: %+v", err)
+		}
+
+		buf := new(bytes.Buffer)
+		err = Write(buf, tensor)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		hdr := buf.Len() - len(data)*8
+		if hdr <= ctxChunk {
+			t.Fatalf("header too small: %d bytes", hdr)
+		}
+
+		w := &cancelWriter{w: io.Discard, n: hdr + 10*8 - 1, cancel: cancel}
+		err = WriteContext(ctx, w, tensor)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+		}
+		if got, want := err.Error(), "after 10 elements"; !strings.Contains(got, want) {
+			t.Fatalf("invalid error message: got=%q, want=%q", got, want)
+		}
+	})
+
+	t.Run("canceled-array", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		arr := loadArray(t, "../testdata/data_float64_2x3_corder.npy")
+		err := WriteContext(ctx, io.Discard, arr)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+		}
+	})
+}
+
+func TestReadContext(t *testing.T) {
+	data := make([]int32, 100)
+	for i := range data {
+		data[i] = int32(i)
+	}
+	buf := new(bytes.Buffer)
+	err := Write(buf, data)
+	if err != nil {
+		t.Fatalf("could not write data: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	t.Run("ok", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("could not create reader: %+v", err)
+		}
+		var got []int32
+		err = r.ReadContext(context.Background(), &got)
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if !reflect.DeepEqual(got, data) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, data)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		ptr  any
+	}{
+		{"slice", new([]int32)},
+		{"array", new(Array)},
+		{"tensor", new(Tensor[int32])},
+	} {
+		t.Run("canceled-"+tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			r, err := NewReader(&cancelReader{
+				r:      iotest.OneByteReader(bytes.NewReader(raw)),
+				n:      len(raw) - 4*100 + 4*20 - 1,
+				cancel: cancel,
+			})
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			err = r.ReadContext(ctx, tc.ptr)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+			}
+			if got, want := err.Error(), "after 20 elements"; !strings.Contains(got, want) {
+				t.Fatalf("invalid error message: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
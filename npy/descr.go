@@ -7,8 +7,10 @@ package npy
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,8 +18,6 @@ import (
 
 	py "github.com/nlpodyssey/gopickle/types"
 	"github.com/sbinet/npyio/npy/float16"
-	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/unicode/utf32"
 )
 
 // ArrayDescr describes a numpy data type.
@@ -54,20 +54,14 @@ func newDescrFromStr(typ string, flags int) (*ArrayDescr, error) {
 		return nil, fmt.Errorf("data type %q not understood", typ)
 	}
 
-	descr := typ
-	switch {
-	case strings.HasPrefix(typ, "<"):
-		descr = descr[1:]
-		dt.order = binary.LittleEndian
-	case strings.HasPrefix(typ, "="):
-		descr = descr[1:]
-		dt.order = nativeEndian
-	case strings.HasPrefix(typ, "|"):
-		descr = descr[1:]
-		dt.order = nil
-	case strings.HasPrefix(typ, ">"):
+	var (
+		descr = typ
+		order = byte('=')
+	)
+	switch typ[0] {
+	case '<', '>', '=', '|':
+		order = typ[0]
 		descr = descr[1:]
-		dt.order = binary.BigEndian
 	}
 
 	if len(descr) == 0 {
@@ -79,14 +73,78 @@ func newDescrFromStr(typ string, flags int) (*ArrayDescr, error) {
 		return nil, fmt.Errorf("datetime string not implemented")
 	}
 
+	if v, ok := descrAliases[descr]; ok {
+		descr = v
+	}
+
 	err := dt.init(descr)
 	if err != nil {
 		return nil, err
 	}
 
+	if dt.hasOrder() {
+		switch order {
+		case '<':
+			dt.order = binary.LittleEndian
+		case '>':
+			dt.order = binary.BigEndian
+		default:
+			dt.order = nativeEndian.ByteOrder
+		}
+	}
+
 	return dt, nil
 }
 
+// descrAliases maps numpy type characters and type names to their
+// kind+size typecode.
+var descrAliases = map[string]string{
+	"?": "b1",
+	"b": "i1",
+	"B": "u1",
+	"h": "i2",
+	"H": "u2",
+	"i": "i4",
+	"I": "u4",
+	"l": "i8",
+	"L": "u8",
+	"q": "i8",
+	"Q": "u8",
+	"e": "f2",
+	"f": "f4",
+	"d": "f8",
+	"g": "f16",
+	"F": "c8",
+	"D": "c16",
+	"G": "c32",
+	"O": "O8",
+	"S": "S0",
+	"U": "U0",
+	"V": "V0",
+
+	"bool":        "b1",
+	"int8":        "i1",
+	"int16":       "i2",
+	"int32":       "i4",
+	"int64":       "i8",
+	"uint8":       "u1",
+	"uint16":      "u2",
+	"uint32":      "u4",
+	"uint64":      "u8",
+	"float16":     "f2",
+	"float32":     "f4",
+	"float64":     "f8",
+	"complex64":   "c8",
+	"complex128":  "c16",
+	"longdouble":  "f16",
+	"clongdouble": "c32",
+	"float128":    "f16",
+	"complex256":  "c32",
+	"object":      "O8",
+	"bytes":       "S0",
+	"str":         "U0",
+}
+
 func isDatetimeStr(typ string) bool {
 	if len(typ) < 2 {
 		return false
@@ -121,10 +179,15 @@ func (dt *ArrayDescr) init(descr string) error {
 		case 'c':
 			dt.align = dt.esize / 2
 		case 'O':
-			// dt.esize = 8
+			dt.esize = 8
 			dt.align = 8
+			dt.flags |= 63
 		case 'S':
 			dt.align = 1
+		case 'U':
+			dt.esize *= 4 // UCS-4 code points.
+			dt.align = 4
+			dt.flags |= 8
 		case 'V':
 			dt.align = 1
 		}
@@ -133,6 +196,18 @@ func (dt *ArrayDescr) init(descr string) error {
 	return nil
 }
 
+// hasOrder returns whether the byte order is relevant for this data type.
+func (dt ArrayDescr) hasOrder() bool {
+	switch dt.kind {
+	case 'i', 'u', 'f', 'c', 'm', 'M':
+		return dt.esize > 1
+	case 'U':
+		return true
+	default:
+		return false
+	}
+}
+
 type structFields map[string]structField
 type structField struct {
 	dtype  ArrayDescr
@@ -316,194 +391,595 @@ func (dt *ArrayDescr) PySetState(arg any) error {
 	}
 
 	if meta.Len() > 0 {
-		return fmt.Errorf("dtype with metadata not handled (yet?)")
+		v, err := toNative(&meta)
+		if err != nil {
+			return fmt.Errorf("could not convert dtype metadata: %w", err)
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid dtype metadata (non-string keys)")
+		}
+		dt.meta = m
 	}
 
 	return nil
 }
 
-func (dt ArrayDescr) unmarshal(raw []byte, shape []int) (any, error) {
-	// FIXME(sbinet): handle ndims
-	// FIXME(sbinet): handle sub-arrays ?
-	// FIXME(sbinet): handle strides
+// ParseDescr parses a numpy data type description.
+//
+// ParseDescr understands the descriptions found in the header of NumPy
+// data files:
+//   - simple data types, e.g. "<f8", "|b1", "|S4", "<U10" or "|O",
+//   - structured data types, e.g. "[('x', '<f8'), ('y', '<i4', (2,))]",
+//
+// as well as the following numpy forms:
+//   - type names and type characters, e.g. "float64", "int32" or "d",
+//   - sub-array data types, e.g. "(2,3)<f8",
+//   - comma-separated structured data types, e.g. "i4, (2,3)f8",
+//   - dict-based structured data types, e.g.
+//     "{'names': ['x', 'y'], 'formats': ['<f8', '<i4'], 'offsets': [0, 8], 'itemsize': 16}".
+//
+// Byte order characters are resolved, so "=f8" describes a "<f8" data type
+// on little-endian machines.
+func ParseDescr(descr string) (ArrayDescr, error) {
+	var (
+		str     = strings.TrimSpace(descr)
+		v   any = str
+	)
+	if str == "" {
+		return ArrayDescr{}, &DescrError{Descr: descr, Op: "parse", Err: fmt.Errorf("empty data type description")}
+	}
+	if strings.ContainsAny(str[:1], "[{'\"") {
+		lit, err := parsePyLiteral(str)
+		if err != nil {
+			return ArrayDescr{}, &DescrError{Descr: descr, Op: "parse", Err: err}
+		}
+		v = lit
+	}
 
-	if dt.subarr != nil {
-		return nil, fmt.Errorf("sub-arrays not handled")
+	dt, err := newDescrFromLiteral(v)
+	if err != nil {
+		return ArrayDescr{}, &DescrError{Descr: descr, Op: "parse", Err: err}
 	}
+	return dt, nil
+}
 
-	switch dt.kind {
-	case 'b':
-		data := make([]bool, len(raw))
-		for i, v := range raw {
-			if v == 0 {
+// newDescrFromLiteral creates a data type from its python literal
+// description (a string, a list of fields or a dict.)
+func newDescrFromLiteral(v any) (ArrayDescr, error) {
+	switch v := v.(type) {
+	case string:
+		return newDescrFromSpec(v)
+
+	case *py.List:
+		var (
+			names  []string
+			dtypes []ArrayDescr
+			offset = 0
+			offs   []int
+		)
+		for i, elem := range *v {
+			tup, ok := elem.(*py.Tuple)
+			if !ok || (tup.Len() != 2 && tup.Len() != 3) {
+				return ArrayDescr{}, fmt.Errorf("invalid field %d description %v", i, elem)
+			}
+			name, ok := tup.Get(0).(string)
+			if !ok {
+				return ArrayDescr{}, fmt.Errorf("invalid field %d name type %T", i, tup.Get(0))
+			}
+			fdt, err := newDescrFromLiteral(tup.Get(1))
+			if err != nil {
+				return ArrayDescr{}, fmt.Errorf("invalid field %q: %w", name, err)
+			}
+			if tup.Len() == 3 {
+				shape, err := shapeFromLiteral(tup.Get(2))
+				if err != nil {
+					return ArrayDescr{}, fmt.Errorf("invalid field %q shape: %w", name, err)
+				}
+				fdt, err = newSubarrayDescr(fdt, shape)
+				if err != nil {
+					return ArrayDescr{}, fmt.Errorf("invalid field %q: %w", name, err)
+				}
+			}
+			if name == "" {
+				// padding bytes.
+				offset += fdt.esize
 				continue
 			}
-			data[i] = true
+			names = append(names, name)
+			dtypes = append(dtypes, fdt)
+			offs = append(offs, offset)
+			offset += fdt.esize
 		}
-		return data, nil
+		return newStructDescr(names, dtypes, offs, offset)
 
-	case 'i':
-		switch dt.esize {
-		case 1:
-			data := make([]int8, len(raw))
-			for i, v := range raw {
-				data[i] = int8(v)
-			}
-			return data, nil
+	case *py.Dict:
+		return newDescrFromDict(v)
 
-		case 2:
-			data := make([]int16, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, int16(dt.order.Uint16(raw[i:])))
-			}
-			return data, nil
+	default:
+		return ArrayDescr{}, fmt.Errorf("invalid data type description type %T", v)
+	}
+}
 
-		case 4:
-			data := make([]int32, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, int32(dt.order.Uint32(raw[i:])))
-			}
-			return data, nil
+// newDescrFromSpec creates a data type from a string specification,
+// like "<f8", "(2,3)<f8" or "i4, (2,3)f8".
+func newDescrFromSpec(spec string) (ArrayDescr, error) {
+	spec = strings.TrimSpace(spec)
 
-		case 8:
-			data := make([]int64, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, int64(dt.order.Uint64(raw[i:])))
+	// comma-separated fields.
+	var (
+		toks  []string
+		depth = 0
+		beg   = 0
+	)
+	for i, c := range spec {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				toks = append(toks, spec[beg:i])
+				beg = i + 1
 			}
-			return data, nil
+		}
+	}
+	if len(toks) > 0 {
+		if tail := strings.TrimSpace(spec[beg:]); tail != "" {
+			toks = append(toks, tail)
+		}
+		var (
+			names  = make([]string, len(toks))
+			dtypes = make([]ArrayDescr, len(toks))
+			offs   = make([]int, len(toks))
+			offset = 0
+		)
+		for i, tok := range toks {
+			fdt, err := newDescrFromSpec(tok)
+			if err != nil {
+				return ArrayDescr{}, err
+			}
+			names[i] = fmt.Sprintf("f%d", i)
+			dtypes[i] = fdt
+			offs[i] = offset
+			offset += fdt.esize
+		}
+		return newStructDescr(names, dtypes, offs, offset)
+	}
 
-		default:
-			return nil, fmt.Errorf("unhandled esize=%d for kind=%q", dt.esize, dt.kind)
+	// sub-array.
+	if strings.HasPrefix(spec, "(") {
+		end := strings.Index(spec, ")")
+		if end < 0 {
+			return ArrayDescr{}, fmt.Errorf("invalid sub-array shape %q", spec)
+		}
+		lit, err := parsePyLiteral(spec[:end+1])
+		if err != nil {
+			return ArrayDescr{}, fmt.Errorf("invalid sub-array shape %q: %w", spec, err)
+		}
+		shape, err := shapeFromLiteral(lit)
+		if err != nil {
+			return ArrayDescr{}, fmt.Errorf("invalid sub-array shape %q: %w", spec, err)
 		}
+		base, err := newDescrFromSpec(spec[end+1:])
+		if err != nil {
+			return ArrayDescr{}, err
+		}
+		return newSubarrayDescr(base, shape)
+	}
 
-	case 'u':
-		switch dt.esize {
-		case 1:
-			data := make([]uint8, len(raw))
-			copy(data, raw)
-			return data, nil
+	const flags = 0
+	dt, err := newDescrFromStr(spec, flags)
+	if err != nil {
+		return ArrayDescr{}, err
+	}
+	var ok bool
+	switch dt.kind {
+	case 'b':
+		ok = dt.esize == 1
+	case 'i', 'u':
+		ok = dt.esize == 1 || dt.esize == 2 || dt.esize == 4 || dt.esize == 8
+	case 'f':
+		ok = dt.esize == 2 || dt.esize == 4 || dt.esize == 8 || dt.esize == 16
+	case 'c':
+		ok = dt.esize == 8 || dt.esize == 16 || dt.esize == 32
+	case 'S', 'U', 'V', 'O':
+		ok = dt.esize >= 0
+	}
+	if !ok {
+		return ArrayDescr{}, fmt.Errorf("data type %q not understood", spec)
+	}
+	return *dt, nil
+}
 
-		case 2:
-			data := make([]uint16, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, dt.order.Uint16(raw[i:]))
+func newDescrFromDict(dict *py.Dict) (ArrayDescr, error) {
+	get := func(key string) (*py.List, error) {
+		v, ok := dict.Get(key)
+		if !ok {
+			return nil, nil
+		}
+		switch v := v.(type) {
+		case *py.List:
+			return v, nil
+		case *py.Tuple:
+			lst := py.NewList()
+			for _, e := range *v {
+				lst.Append(e)
 			}
-			return data, nil
+			return lst, nil
+		default:
+			return nil, fmt.Errorf("invalid %q type %T", key, v)
+		}
+	}
 
-		case 4:
-			data := make([]uint32, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, dt.order.Uint32(raw[i:]))
-			}
-			return data, nil
+	names, err := get("names")
+	if err != nil {
+		return ArrayDescr{}, err
+	}
+	formats, err := get("formats")
+	if err != nil {
+		return ArrayDescr{}, err
+	}
+	offsets, err := get("offsets")
+	if err != nil {
+		return ArrayDescr{}, err
+	}
+	if names == nil || formats == nil {
+		return ArrayDescr{}, fmt.Errorf("missing 'names' or 'formats' keys")
+	}
+	if names.Len() != formats.Len() || (offsets != nil && offsets.Len() != names.Len()) {
+		return ArrayDescr{}, fmt.Errorf("inconsistent 'names', 'formats' and 'offsets' lengths")
+	}
 
-		case 8:
-			data := make([]uint64, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, dt.order.Uint64(raw[i:]))
+	var (
+		n      = names.Len()
+		fnames = make([]string, n)
+		dtypes = make([]ArrayDescr, n)
+		offs   = make([]int, n)
+		offset = 0
+		end    = 0
+	)
+	for i := 0; i < n; i++ {
+		name, ok := names.Get(i).(string)
+		if !ok {
+			return ArrayDescr{}, fmt.Errorf("invalid field %d name type %T", i, names.Get(i))
+		}
+		fdt, err := newDescrFromLiteral(formats.Get(i))
+		if err != nil {
+			return ArrayDescr{}, fmt.Errorf("invalid field %q: %w", name, err)
+		}
+		if offsets != nil {
+			off, ok := offsets.Get(i).(int)
+			if !ok || off < 0 {
+				return ArrayDescr{}, fmt.Errorf("invalid field %q offset %v", name, offsets.Get(i))
 			}
-			return data, nil
-
-		default:
-			return nil, fmt.Errorf("unhandled esize=%d for kind=%q", dt.esize, dt.kind)
+			offset = off
+		}
+		fnames[i] = name
+		dtypes[i] = fdt
+		offs[i] = offset
+		offset += fdt.esize
+		if offset > end {
+			end = offset
 		}
+	}
 
-	case 'f':
-		switch dt.esize {
-		case 2:
-			data := make([]float16.Num, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, float16.Float16Frombits(dt.order.Uint16(raw[i:])))
-			}
-			return data, nil
+	if v, ok := dict.Get("itemsize"); ok {
+		size, ok := v.(int)
+		if !ok || size < end {
+			return ArrayDescr{}, fmt.Errorf("invalid itemsize %v", v)
+		}
+		end = size
+	}
 
-		case 4:
-			data := make([]float32, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, math.Float32frombits(dt.order.Uint32(raw[i:])))
-			}
-			return data, nil
+	return newStructDescr(fnames, dtypes, offs, end)
+}
 
-		case 8:
-			data := make([]float64, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, math.Float64frombits(dt.order.Uint64(raw[i:])))
+func shapeFromLiteral(v any) ([]int, error) {
+	switch v := v.(type) {
+	case int:
+		return []int{v}, nil
+	case *py.Tuple:
+		shape := make([]int, v.Len())
+		for i := range shape {
+			dim, ok := v.Get(i).(int)
+			if !ok || dim < 0 {
+				return nil, fmt.Errorf("invalid dimension %v", v.Get(i))
 			}
-			return data, nil
+			shape[i] = dim
+		}
+		return shape, nil
+	default:
+		return nil, fmt.Errorf("invalid shape type %T", v)
+	}
+}
 
-		default:
-			return nil, fmt.Errorf("unhandled esize=%d for kind=%q", dt.esize, dt.kind)
+// npyFromFields is the set of numpy data type flags a structured data type
+// inherits from its fields.
+const npyFromFields = 0x1b
+
+func newStructDescr(names []string, dtypes []ArrayDescr, offsets []int, size int) (ArrayDescr, error) {
+	dt := ArrayDescr{
+		kind:   'V',
+		esize:  size,
+		align:  1,
+		flags:  0x10,
+		names:  names,
+		fields: make(structFields, len(names)),
+	}
+	for i, name := range names {
+		if _, dup := dt.fields[name]; dup {
+			return ArrayDescr{}, fmt.Errorf("duplicate field name %q", name)
 		}
+		dt.fields[name] = structField{dtype: dtypes[i], offset: uint32(offsets[i])}
+		dt.flags |= dtypes[i].flags & npyFromFields
+	}
+	return dt, nil
+}
 
-	case 'c':
-		switch dt.esize {
-		case 8:
-			data := make([]complex64, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, complex(
-					math.Float32frombits(dt.order.Uint32(raw[i+0:])),
-					math.Float32frombits(dt.order.Uint32(raw[i+4:])),
-				))
-			}
-			return data, nil
+func newSubarrayDescr(base ArrayDescr, shape []int) (ArrayDescr, error) {
+	n, err := shapeSize(shape)
+	if err != nil {
+		return ArrayDescr{}, err
+	}
+	if n != 0 && base.esize > math.MaxInt32/n {
+		return ArrayDescr{}, fmt.Errorf("sub-array %v of [%c%d] is too large: %w", shape, base.kind, base.esize, ErrShapeMismatch)
+	}
+	return ArrayDescr{
+		kind:  'V',
+		esize: base.esize * n,
+		align: base.align,
+		flags: base.flags,
+		subarr: &subarrayDescr{
+			dtype: base,
+			shape: shape,
+		},
+	}, nil
+}
 
-		case 16:
-			data := make([]complex128, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, complex(
-					math.Float64frombits(dt.order.Uint64(raw[i+0:])),
-					math.Float64frombits(dt.order.Uint64(raw[i+8:])),
-				))
-			}
-			return data, nil
+// Kind returns the character code identifying the general kind of data:
+// 'b' (boolean), 'i' (signed integer), 'u' (unsigned integer),
+// 'f' (floating point), 'c' (complex floating point), 'S' (bytes),
+// 'U' (unicode string), 'V' (void, structured and sub-array data types)
+// or 'O' (python objects).
+func (dt ArrayDescr) Kind() byte {
+	return dt.kind
+}
+
+// ItemSize returns the size in bytes of an element of this data type.
+func (dt ArrayDescr) ItemSize() int {
+	return dt.esize
+}
+
+// Alignment returns the alignment in bytes required for this data type.
+func (dt ArrayDescr) Alignment() int {
+	return dt.align
+}
 
+// ByteOrder returns the byte order of this data type.
+// ByteOrder returns nil for data types where the byte order is not
+// relevant (e.g. booleans, bytes or structured data types.)
+func (dt ArrayDescr) ByteOrder() binary.ByteOrder {
+	if dt.order == nativeEndian {
+		return nativeEndian.ByteOrder
+	}
+	return dt.order
+}
+
+// Names returns the names of the fields of a structured data type,
+// in order.
+// Names returns nil for non-structured data types.
+func (dt ArrayDescr) Names() []string {
+	return dt.names
+}
+
+// Field returns the data type and byte offset of the named field of
+// a structured data type.
+func (dt ArrayDescr) Field(name string) (descr ArrayDescr, offset int, ok bool) {
+	field, ok := dt.fields[name]
+	if !ok {
+		return descr, 0, false
+	}
+	return field.dtype, int(field.offset), true
+}
+
+// SubArray returns the element data type and the shape of a sub-array
+// data type.
+func (dt ArrayDescr) SubArray() (descr ArrayDescr, shape []int, ok bool) {
+	if dt.subarr == nil {
+		return descr, nil, false
+	}
+	return dt.subarr.dtype, dt.subarr.shape, true
+}
+
+// Metadata returns the metadata attached to this data type, if any.
+func (dt ArrayDescr) Metadata() map[string]any {
+	return dt.meta
+}
+
+// Descr returns the canonical numpy description of this data type,
+// as written in the header of NumPy data files.
+//
+// Simple data types are described with their type string (e.g. "<f8"),
+// structured data types with their list of fields
+// (e.g. "[('x', '<f8'), ('y', '<i4', (2,))]").
+// Sub-array data types are described as "(2, 3)<f8".
+func (dt ArrayDescr) Descr() string {
+	switch {
+	case dt.names != nil:
+		o := new(strings.Builder)
+		dt.writeFields(o)
+		return o.String()
+	case dt.subarr != nil:
+		return shapeString(dt.subarr.shape) + dt.subarr.dtype.Descr()
+	default:
+		return dt.typestr()
+	}
+}
+
+// typestr returns the numpy type string of a simple data type
+// (e.g. "<f8", "|S4", "<U10", ...)
+func (dt ArrayDescr) typestr() string {
+	order := orderToString(dt.ByteOrder())
+	switch dt.kind {
+	case 'O':
+		return order + "O"
+	default:
+		return order + dt.typecode()
+	}
+}
+
+// typecode returns the kind+size type code of a data type
+// (e.g. "f8", "U10", "V16", ...)
+func (dt ArrayDescr) typecode() string {
+	switch dt.kind {
+	case 'U':
+		return fmt.Sprintf("U%d", dt.esize/4)
+	default:
+		return fmt.Sprintf("%c%d", dt.kind, dt.esize)
+	}
+}
+
+func (dt ArrayDescr) writeFields(o *strings.Builder) {
+	names := make([]string, len(dt.names))
+	copy(names, dt.names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return dt.fields[names[i]].offset < dt.fields[names[j]].offset
+	})
+
+	var (
+		offset = 0
+		first  = true
+	)
+	sep := func() {
+		if !first {
+			o.WriteString(", ")
+		}
+		first = false
+	}
+	o.WriteString("[")
+	for _, name := range names {
+		field := dt.fields[name]
+		if pad := int(field.offset) - offset; pad > 0 {
+			sep()
+			fmt.Fprintf(o, "('', '|V%d')", pad)
+		}
+		sep()
+		fmt.Fprintf(o, "(%s, ", pyQuote(name))
+		fdt := field.dtype
+		var shape []int
+		if fdt.subarr != nil {
+			shape = fdt.subarr.shape
+			fdt = fdt.subarr.dtype
+		}
+		switch {
+		case fdt.names != nil:
+			fdt.writeFields(o)
 		default:
-			return nil, fmt.Errorf("unhandled esize=%d for kind=%q", dt.esize, dt.kind)
+			o.WriteString(pyQuote(fdt.Descr()))
 		}
+		if shape != nil {
+			fmt.Fprintf(o, ", %s", shapeString(shape))
+		}
+		o.WriteString(")")
+		if end := int(field.offset) + field.dtype.esize; end > offset {
+			offset = end
+		}
+	}
+	if pad := dt.esize - offset; pad > 0 {
+		sep()
+		fmt.Fprintf(o, "('', '|V%d')", pad)
+	}
+	o.WriteString("]")
+}
 
-	case 'S':
-		switch len(shape) {
-		case 0:
-			return string(raw), nil // FIXME(sbinet): use subset ? (shape/dims/...)
-		default:
-			data := make([]string, 0, len(raw)/dt.esize)
-			for i := 0; i < len(raw); i += dt.esize {
-				data = append(data, string(raw[i:i+dt.esize])) // FIXME(sbinet): no-alloc ?
-			}
-			return data, nil
+// pyQuote returns a single-quoted python string literal for s.
+func pyQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "'", "\\'")
+	return "'" + s + "'"
+}
+
+// unmarshal decodes the provided raw array data, following the data type
+// layout and byte order.
+func (dt ArrayDescr) unmarshal(raw []byte, shape []int) (any, error) {
+	data, err := dt.decodeArray(raw, shape)
+	if err != nil {
+		var dec *decodeError
+		if errors.As(err, &dec) {
+			// corrupt array data, not an unsupported data type.
+			return nil, err
 		}
+		return nil, &DescrError{Descr: dt.Descr(), Op: "decode", Err: err}
+	}
+	return data, nil
+}
 
-	case 'U':
-		order := utf32.BigEndian
-		if dt.order == binary.LittleEndian {
-			order = utf32.LittleEndian
-		}
-		dec := utf32.UTF32(order, utf32.IgnoreBOM).NewDecoder()
-		switch len(shape) {
-		case 0:
-			data, err := decodeUTF(dec, raw)
+func (dt ArrayDescr) decodeArray(raw []byte, shape []int) (any, error) {
+	// FIXME(sbinet): handle ndims
+	// FIXME(sbinet): handle sub-arrays ?
+	// FIXME(sbinet): handle strides
+
+	if dt.subarr != nil {
+		return nil, fmt.Errorf("sub-arrays not handled")
+	}
+
+	switch dt.kind {
+	case 'b', 'i', 'u', 'f', 'c':
+		if dt.isExtended() {
+			return dt.decodeExtended(raw)
+		}
+		rt, err := dt.goType()
+		if err != nil {
+			return nil, fmt.Errorf("unhandled esize=%d for kind=%q", dt.esize, dt.kind)
+		}
+		n := len(raw) / dt.esize
+		data := reflect.MakeSlice(reflect.SliceOf(rt), n, n).Interface()
+		dt.decode(data, raw)
+		return data, nil
+
+	case 'S', 'U':
+		switch {
+		case len(shape) == 0:
+			data, err := dt.decodeString(raw)
 			if err != nil {
-				return nil, fmt.Errorf("could not decode utf array: %w", err)
+				return nil, &decodeError{elem: 0, err: fmt.Errorf("npy: could not decode string: %w", err)}
 			}
 			return data, nil
 
+		case dt.esize == 0:
+			return make([]string, numElems(shape)), nil
+
 		default:
 			data := make([]string, 0, len(raw)/dt.esize)
 			for i := 0; i < len(raw); i += dt.esize {
-				v, err := decodeUTF(dec, raw[i:i+dt.esize])
+				v, err := dt.decodeString(raw[i : i+dt.esize])
 				if err != nil {
-					return nil, fmt.Errorf("could not decode utf array element %d: %w", i/dt.esize, err)
+					return nil, &decodeError{
+						elem: i / dt.esize,
+						err:  fmt.Errorf("npy: could not decode string element %d: %w", i/dt.esize, err),
+					}
 				}
 				data = append(data, v)
 			}
 			return data, nil
 		}
 
+	case 'V':
+		if dt.esize == 0 {
+			return make([][]byte, numElems(shape)), nil
+		}
+		data := make([][]byte, 0, len(raw)/dt.esize)
+		for i := 0; i < len(raw); i += dt.esize {
+			data = append(data, raw[i:i+dt.esize:i+dt.esize])
+		}
+		return data, nil
+
 	case 'O':
-		pkl := newUnpickler(bytes.NewReader(raw))
-		data, err := pkl.Load()
+		data, err := unpickle(raw)
 		if err != nil {
-			return nil, fmt.Errorf("could not unpickle data: %w", err)
+			return nil, &decodeError{elem: -1, err: fmt.Errorf("npy: could not unpickle data: %w", err)}
 		}
 		return data, nil
 
@@ -512,6 +988,316 @@ func (dt ArrayDescr) unmarshal(raw []byte, shape []int) (any, error) {
 	}
 }
 
+// marshal encodes the provided data slice, following the data type
+// layout and byte order.
+func (dt ArrayDescr) marshal(data any) ([]byte, error) {
+	raw, err := dt.encodeArray(data)
+	if err != nil {
+		return nil, &DescrError{Descr: dt.Descr(), Op: "encode", Err: err}
+	}
+	return raw, nil
+}
+
+func (dt ArrayDescr) encodeArray(data any) ([]byte, error) {
+	if dt.subarr != nil || (dt.names != nil && dt.kind != 'V') {
+		return nil, fmt.Errorf("sub-arrays and structured arrays not handled: %w", ErrInvalidType)
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("invalid data type %T: %w", data, ErrTypeMismatch)
+	}
+
+	switch dt.kind {
+	case 'b', 'i', 'u', 'f', 'c':
+		if dt.isExtended() {
+			return dt.encodeExtended(data)
+		}
+		rt, err := dt.goType()
+		if err != nil {
+			return nil, err
+		}
+		rv := reflect.ValueOf(data)
+		if rv.Kind() != reflect.Slice || rv.Type().Elem() != rt {
+			return nil, mismatch()
+		}
+		raw := make([]byte, rv.Len()*dt.esize)
+		dt.encode(raw, data)
+		return raw, nil
+
+	case 'S', 'U':
+		var strs []string
+		switch data := data.(type) {
+		case string:
+			strs = []string{data}
+		case []string:
+			strs = data
+		default:
+			return nil, mismatch()
+		}
+		raw := make([]byte, len(strs)*dt.esize)
+		for i, str := range strs {
+			beg := i * dt.esize
+			switch dt.kind {
+			case 'S':
+				if len(str) > dt.esize {
+					return nil, fmt.Errorf("string %q too long", str)
+				}
+				copy(raw[beg:], str)
+			default:
+				if utf8.RuneCountInString(str) > dt.esize/4 {
+					return nil, fmt.Errorf("string %q too long", str)
+				}
+				for _, r := range str {
+					dt.order.PutUint32(raw[beg:], uint32(r))
+					beg += 4
+				}
+			}
+		}
+		return raw, nil
+
+	case 'V':
+		vs, ok := data.([][]byte)
+		if !ok {
+			return nil, mismatch()
+		}
+		raw := make([]byte, 0, len(vs)*dt.esize)
+		for i, v := range vs {
+			if len(v) != dt.esize {
+				return nil, fmt.Errorf("invalid size %d for element %d", len(v), i)
+			}
+			raw = append(raw, v...)
+		}
+		return raw, nil
+
+	default:
+		return nil, fmt.Errorf("unknown dtype [%c%d]: %w", dt.kind, dt.esize, ErrInvalidType)
+	}
+}
+
+// encode encodes the numeric data slice into raw, following the data type
+// byte order.
+// raw must be large enough to hold all the elements of data.
+func (dt ArrayDescr) encode(raw []byte, data any) {
+	switch data := data.(type) {
+	case []bool:
+		for i, v := range data {
+			raw[i] = 0
+			if v {
+				raw[i] = 1
+			}
+		}
+	case []int8:
+		for i, v := range data {
+			raw[i] = byte(v)
+		}
+	case []uint8:
+		copy(raw, data)
+	case []int16:
+		for i, v := range data {
+			dt.order.PutUint16(raw[2*i:], uint16(v))
+		}
+	case []uint16:
+		for i, v := range data {
+			dt.order.PutUint16(raw[2*i:], v)
+		}
+	case []int32:
+		for i, v := range data {
+			dt.order.PutUint32(raw[4*i:], uint32(v))
+		}
+	case []uint32:
+		for i, v := range data {
+			dt.order.PutUint32(raw[4*i:], v)
+		}
+	case []int64:
+		for i, v := range data {
+			dt.order.PutUint64(raw[8*i:], uint64(v))
+		}
+	case []uint64:
+		for i, v := range data {
+			dt.order.PutUint64(raw[8*i:], v)
+		}
+	case []float16.Num:
+		for i, v := range data {
+			dt.order.PutUint16(raw[2*i:], v.Uint16())
+		}
+	case []float32:
+		for i, v := range data {
+			dt.order.PutUint32(raw[4*i:], math.Float32bits(v))
+		}
+	case []float64:
+		for i, v := range data {
+			dt.order.PutUint64(raw[8*i:], math.Float64bits(v))
+		}
+	case []complex64:
+		for i, v := range data {
+			dt.order.PutUint32(raw[8*i+0:], math.Float32bits(real(v)))
+			dt.order.PutUint32(raw[8*i+4:], math.Float32bits(imag(v)))
+		}
+	case []complex128:
+		for i, v := range data {
+			dt.order.PutUint64(raw[16*i+0:], math.Float64bits(real(v)))
+			dt.order.PutUint64(raw[16*i+8:], math.Float64bits(imag(v)))
+		}
+	default:
+		panic(fmt.Errorf("npy: invalid data type %T", data))
+	}
+}
+
+// decode decodes the raw array data into the numeric data slice, following
+// the data type byte order.
+// raw must hold at least as many elements as data.
+func (dt ArrayDescr) decode(data any, raw []byte) {
+	switch data := data.(type) {
+	case []bool:
+		for i := range data {
+			data[i] = raw[i] != 0
+		}
+	case []int8:
+		for i := range data {
+			data[i] = int8(raw[i])
+		}
+	case []uint8:
+		copy(data, raw)
+	case []int16:
+		for i := range data {
+			data[i] = int16(dt.order.Uint16(raw[2*i:]))
+		}
+	case []uint16:
+		for i := range data {
+			data[i] = dt.order.Uint16(raw[2*i:])
+		}
+	case []int32:
+		for i := range data {
+			data[i] = int32(dt.order.Uint32(raw[4*i:]))
+		}
+	case []uint32:
+		for i := range data {
+			data[i] = dt.order.Uint32(raw[4*i:])
+		}
+	case []int64:
+		for i := range data {
+			data[i] = int64(dt.order.Uint64(raw[8*i:]))
+		}
+	case []uint64:
+		for i := range data {
+			data[i] = dt.order.Uint64(raw[8*i:])
+		}
+	case []float16.Num:
+		for i := range data {
+			data[i] = float16.Float16Frombits(dt.order.Uint16(raw[2*i:]))
+		}
+	case []float32:
+		for i := range data {
+			data[i] = math.Float32frombits(dt.order.Uint32(raw[4*i:]))
+		}
+	case []float64:
+		for i := range data {
+			data[i] = math.Float64frombits(dt.order.Uint64(raw[8*i:]))
+		}
+	case []complex64:
+		for i := range data {
+			data[i] = complex(
+				math.Float32frombits(dt.order.Uint32(raw[8*i+0:])),
+				math.Float32frombits(dt.order.Uint32(raw[8*i+4:])),
+			)
+		}
+	case []complex128:
+		for i := range data {
+			data[i] = complex(
+				math.Float64frombits(dt.order.Uint64(raw[16*i+0:])),
+				math.Float64frombits(dt.order.Uint64(raw[16*i+8:])),
+			)
+		}
+	default:
+		panic(fmt.Errorf("npy: invalid data type %T", data))
+	}
+}
+
+// goType returns the Go type of the elements of this data type.
+func (dt ArrayDescr) goType() (reflect.Type, error) {
+	var rt reflect.Type
+	switch dt.kind {
+	case 'b':
+		rt = boolType
+	case 'i':
+		switch dt.esize {
+		case 1:
+			rt = int8Type
+		case 2:
+			rt = int16Type
+		case 4:
+			rt = int32Type
+		case 8:
+			rt = int64Type
+		}
+	case 'u':
+		switch dt.esize {
+		case 1:
+			rt = uint8Type
+		case 2:
+			rt = uint16Type
+		case 4:
+			rt = uint32Type
+		case 8:
+			rt = uint64Type
+		}
+	case 'f':
+		switch dt.esize {
+		case 2:
+			rt = float16Type
+		case 4:
+			rt = float32Type
+		case 8:
+			rt = float64Type
+		case 16:
+			rt = bigFloatType
+		}
+	case 'c':
+		switch dt.esize {
+		case 8:
+			rt = complex64Type
+		case 16:
+			rt = complex128Type
+		case 32:
+			rt = bigComplexType
+		}
+	case 'S', 'U':
+		rt = stringType
+	case 'V':
+		// opaque data and structured records, as raw bytes.
+		rt = voidType
+	case 'O':
+		rt = anyType
+	}
+	if rt == nil || dt.subarr != nil || (dt.names != nil && dt.kind != 'V') {
+		return nil, fmt.Errorf("npy: no Go type for dtype [%c%d]: %w", dt.kind, dt.esize, ErrInvalidType)
+	}
+	return rt, nil
+}
+
+func (dt ArrayDescr) unmarshalScalar(raw []byte) (any, error) {
+	if dt.esize >= 0 && len(raw) != dt.esize {
+		return nil, fmt.Errorf(
+			"invalid scalar payload size for dtype [%c%d] (got=%d)",
+			dt.kind, dt.esize, len(raw),
+		)
+	}
+
+	data, err := dt.unmarshal(raw, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice {
+		return data, nil
+	}
+	if rv.Len() != 1 {
+		return nil, fmt.Errorf("invalid scalar payload length (got=%d)", rv.Len())
+	}
+	return rv.Index(0).Interface(), nil
+}
+
 func (dt ArrayDescr) itemsize() int {
 	if dt.esize < 0 {
 		panic(fmt.Errorf("unknown dtype [%c%d]", dt.kind, dt.esize))
@@ -567,27 +1353,31 @@ func (sub subarrayDescr) String() string {
 	return o.String()
 }
 
-func decodeUTF(dec *encoding.Decoder, raw []byte) (string, error) {
-	// FIXME(sbinet): use subset ? (shape/dims/...)
-	vs := make([]byte, 0, utf8.RuneCount(raw))
-	raw, err := dec.Bytes(raw)
-	if err != nil {
-		return "", err
-	}
-	i := 0
-loop:
-	for {
-		r, sz := utf8.DecodeRune(raw[i:])
-		switch r {
-		case utf8.RuneError:
-			if sz == 0 {
-				break loop
+// decodeString decodes a single element of this byte-string ('S') or
+// unicode ('U', UCS-4 code points) data type.
+// Like NumPy, the trailing NUL characters are removed.
+func (dt ArrayDescr) decodeString(raw []byte) (string, error) {
+	switch dt.kind {
+	case 'S':
+		return string(bytes.TrimRight(raw, "\x00")), nil
+	case 'U':
+		if len(raw)%4 != 0 {
+			return "", fmt.Errorf("npy: invalid UCS-4 payload size %d", len(raw))
+		}
+		order := dt.order
+		if order == nil {
+			order = nativeEndian.ByteOrder
+		}
+		str := make([]byte, 0, len(raw)/4)
+		for i := 0; i < len(raw); i += 4 {
+			r := rune(order.Uint32(raw[i:]))
+			if !utf8.ValidRune(r) {
+				return "", fmt.Errorf("npy: invalid code point %#x", uint32(r))
 			}
-			return string(vs), fmt.Errorf("invalid rune")
-		default:
-			vs = utf8.AppendRune(vs, r)
-			i += sz
+			str = utf8.AppendRune(str, r)
 		}
+		return strings.TrimRight(string(str), "\x00"), nil
+	default:
+		return "", fmt.Errorf("npy: invalid string dtype [%c%d]: %w", dt.kind, dt.esize, ErrInvalidType)
 	}
-	return string(vs), nil
 }
@@ -0,0 +1,198 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"encoding/binary"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseDescr(t *testing.T) {
+	re := regexp.MustCompile(`^np\.dtype\("(.*)"\)$`)
+	for _, tc := range dtypeTests {
+		m := re.FindStringSubmatch(tc.code)
+		if m == nil {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseDescr(m[1])
+			if err != nil {
+				t.Fatalf("could not parse %q: %+v", m[1], err)
+			}
+			if want := *tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid descr for %q:\ngot= %v\nwant=%v", m[1], got, want)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		descr string
+		want  string
+	}{
+		{"<f8", "<f8"},
+		{"'<f8'", "<f8"},
+		{"float64", "<f8"},
+		{"d", "<f8"},
+		{">i2", ">i2"},
+		{"<i1", "|i1"},
+		{"?", "|b1"},
+		{"|b1", "|b1"},
+		{"S4", "|S4"},
+		{"<U10", "<U10"},
+		{">U3", ">U3"},
+		{"|O", "|O"},
+		{"object", "|O"},
+		{"V16", "|V16"},
+		{"(2,3)<f8", "(2, 3)<f8"},
+		{"(4,)i2", "(4,)<i2"},
+		{
+			"[('x', '<f8'), ('y', '<i4')]",
+			"[('x', '<f8'), ('y', '<i4')]",
+		},
+		{
+			`[("x", "<f8"), ("y", "<i4", (2,)), ("z", [("a", "|u1"), ("b", ">f4", (2, 2))])]`,
+			"[('x', '<f8'), ('y', '<i4', (2,)), ('z', [('a', '|u1'), ('b', '>f4', (2, 2))])]",
+		},
+		{
+			"[('x', '|u1'), ('', '|V3'), ('y', '<i4')]",
+			"[('x', '|u1'), ('', '|V3'), ('y', '<i4')]",
+		},
+		{
+			"i4, (2,3)f8",
+			"[('f0', '<i4'), ('f1', '<f8', (2, 3))]",
+		},
+		{
+			"{'names': ['x', 'y'], 'formats': ['u1', '<i4'], 'offsets': [0, 4], 'itemsize': 12}",
+			"[('x', '|u1'), ('', '|V3'), ('y', '<i4'), ('', '|V4')]",
+		},
+		{
+			"{'names': ('b', 'a'), 'formats': ('<i4', '<i2'), 'offsets': (2, 0)}",
+			"[('a', '<i2'), ('b', '<i4')]",
+		},
+		{
+			`[('it\'s', '<f4')]`,
+			`[('it\'s', '<f4')]`,
+		},
+	} {
+		t.Run(tc.descr, func(t *testing.T) {
+			dt, err := ParseDescr(tc.descr)
+			if err != nil {
+				t.Fatalf("could not parse %q: %+v", tc.descr, err)
+			}
+			if got, want := dt.Descr(), tc.want; got != want {
+				t.Fatalf("invalid descr:\ngot= %q\nwant=%q", got, want)
+			}
+
+			rt, err := ParseDescr(dt.Descr())
+			if err != nil {
+				t.Fatalf("could not re-parse %q: %+v", dt.Descr(), err)
+			}
+			if got, want := rt.Descr(), dt.Descr(); got != want {
+				t.Fatalf("invalid round-trip:\ngot= %q\nwant=%q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseDescrErrors(t *testing.T) {
+	for _, descr := range []string{
+		"",
+		"<",
+		"x8",
+		"i3",
+		"c4",
+		"M8",
+		"<f8'",
+		"[('x', '<f8')",
+		"[('x', '<f8'), ('x', '<i4')]",
+		"[('x', '<f8', 'y')]",
+		"[(1, '<f8')]",
+		"(2,<f8",
+		"{'names': ['x'], 'formats': []}",
+		"{'names': ['x'], 'formats': ['<f8'], 'itemsize': 4}",
+	} {
+		t.Run(descr, func(t *testing.T) {
+			_, err := ParseDescr(descr)
+			if err == nil {
+				t.Fatalf("expected an error for %q", descr)
+			}
+		})
+	}
+}
+
+func TestArrayDescrAccessors(t *testing.T) {
+	dt, err := ParseDescr("[('x', '>f8'), ('y', '<i4', (2, 3)), ('s', '|S5')]")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+
+	if got, want := dt.Kind(), byte('V'); got != want {
+		t.Fatalf("invalid kind: got=%c, want=%c", got, want)
+	}
+	if got, want := dt.ItemSize(), 8+4*6+5; got != want {
+		t.Fatalf("invalid item size: got=%d, want=%d", got, want)
+	}
+	if got, want := dt.Alignment(), 1; got != want {
+		t.Fatalf("invalid alignment: got=%d, want=%d", got, want)
+	}
+	if got := dt.ByteOrder(); got != nil {
+		t.Fatalf("invalid byte order: got=%v, want=nil", got)
+	}
+	if got, want := dt.Names(), []string{"x", "y", "s"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid names:\ngot= %q\nwant=%q", got, want)
+	}
+	if _, _, ok := dt.SubArray(); ok {
+		t.Fatalf("structured dtype should not be a sub-array")
+	}
+
+	x, off, ok := dt.Field("x")
+	if !ok {
+		t.Fatalf("could not find field x")
+	}
+	if got, want := off, 0; got != want {
+		t.Fatalf("invalid x offset: got=%d, want=%d", got, want)
+	}
+	if got, want := x.ByteOrder(), binary.ByteOrder(binary.BigEndian); got != want {
+		t.Fatalf("invalid x byte order: got=%v, want=%v", got, want)
+	}
+
+	y, off, ok := dt.Field("y")
+	if !ok {
+		t.Fatalf("could not find field y")
+	}
+	if got, want := off, 8; got != want {
+		t.Fatalf("invalid y offset: got=%d, want=%d", got, want)
+	}
+	elem, shape, ok := y.SubArray()
+	if !ok {
+		t.Fatalf("field y should be a sub-array")
+	}
+	if got, want := shape, []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid y shape: got=%v, want=%v", got, want)
+	}
+	if got, want := elem.Descr(), "<i4"; got != want {
+		t.Fatalf("invalid y element descr: got=%q, want=%q", got, want)
+	}
+	if got, want := y.Alignment(), 4; got != want {
+		t.Fatalf("invalid y alignment: got=%d, want=%d", got, want)
+	}
+
+	s, off, ok := dt.Field("s")
+	if !ok {
+		t.Fatalf("could not find field s")
+	}
+	if got, want := off, 32; got != want {
+		t.Fatalf("invalid s offset: got=%d, want=%d", got, want)
+	}
+	if got, want := s.ItemSize(), 5; got != want {
+		t.Fatalf("invalid s item size: got=%d, want=%d", got, want)
+	}
+
+	if _, _, ok := dt.Field("z"); ok {
+		t.Fatalf("unexpected field z")
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// HeaderError describes an invalid NumPy data file header.
+//
+// HeaderErrors satisfy errors.Is(err, ErrInvalidNumPyFormat).
+type HeaderError struct {
+	Offset int64  // offset, in bytes, of the invalid header field from the start of the file.
+	Reason string // description of the problem.
+	Err    error  // underlying error, if any.
+}
+
+func (e *HeaderError) Error() string {
+	msg := fmt.Sprintf("npy: invalid header at offset %d: %s", e.Offset, e.Reason)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *HeaderError) Unwrap() error { return e.Err }
+
+func (e *HeaderError) Is(target error) bool { return target == ErrInvalidNumPyFormat }
+
+// TypeMismatchError describes a Go value that can not hold the array data
+// of a NumPy data file.
+//
+// TypeMismatchErrors satisfy errors.Is(err, ErrTypeMismatch).
+type TypeMismatchError struct {
+	OnDisk    string       // on-disk data type ('<i8', '<f4', ...)
+	Requested reflect.Type // type of the provided Go value.
+	Err       error        // underlying error, if any.
+}
+
+func (e *TypeMismatchError) Error() string {
+	msg := fmt.Sprintf("npy: types don't match (on-disk=%q, requested=%v)", e.OnDisk, e.Requested)
+	if e.Err != nil && e.Err != ErrTypeMismatch {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TypeMismatchError) Unwrap() error { return e.Err }
+
+func (e *TypeMismatchError) Is(target error) bool { return target == ErrTypeMismatch }
+
+// ShapeError describes a Go value that can not hold the array data of a
+// NumPy data file, because of the array shape.
+//
+// ShapeErrors satisfy errors.Is(err, ErrShapeMismatch).
+type ShapeError struct {
+	Got  []int // on-disk shape.
+	Want []int // shape the provided Go value can hold (-1 for dimensions of any size.)
+	Err  error // underlying error, if any.
+}
+
+func (e *ShapeError) Error() string {
+	msg := fmt.Sprintf("npy: invalid dimensions (got=%v, want=%v)", e.Got, e.Want)
+	if e.Err != nil && e.Err != ErrShapeMismatch {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ShapeError) Unwrap() error { return e.Err }
+
+func (e *ShapeError) Is(target error) bool { return target == ErrShapeMismatch }
+
+// PayloadError describes a failure to read the array data of a NumPy data
+// file, e.g. because the file is truncated or holds array data that can not
+// be decoded (an invalid UCS-4 code point, malformed pickled data, ...).
+//
+// PayloadErrors satisfy errors.Is(err, ErrPayload).
+// Truncated array data is reported with an underlying io.ErrUnexpectedEOF.
+type PayloadError struct {
+	Element    int   // index of the first element that could not be read (-1 if unknown.)
+	ByteOffset int64 // offset, in bytes, of the failure from the start of the file.
+	Err        error // underlying error.
+}
+
+func (e *PayloadError) Error() string {
+	return fmt.Sprintf("npy: could not read array data at element %d (offset %d): %v", e.Element, e.ByteOffset, e.Err)
+}
+
+func (e *PayloadError) Unwrap() error { return e.Err }
+
+func (e *PayloadError) Is(target error) bool { return target == ErrPayload }
+
+// LimitError describes a NumPy data file whose header or array data
+// exceed the configured resource limits.
+//
+// LimitErrors satisfy errors.Is(err, ErrLimitExceeded).
+type LimitError struct {
+	Resource string // limited resource, e.g. "header size" or "number of elements".
+	Size     int64  // requested size of the resource (-1 if unknown.)
+	Limit    int64  // maximum size of the resource.
+}
+
+func (e *LimitError) Error() string {
+	if e.Size < 0 {
+		return fmt.Sprintf("npy: %s exceeds limit (%d)", e.Resource, e.Limit)
+	}
+	return fmt.Sprintf("npy: %s (%d) exceeds limit (%d)", e.Resource, e.Size, e.Limit)
+}
+
+func (e *LimitError) Is(target error) bool { return target == ErrLimitExceeded }
+
+// DescrError describes a data type description that could not be parsed,
+// or that is not supported to encode or decode array data.
+//
+// DescrErrors satisfy errors.Is(err, ErrInvalidType).
+type DescrError struct {
+	Descr string // data type description.
+	Op    string // failed operation: "parse", "encode" or "decode".
+	Err   error  // underlying error.
+}
+
+func (e *DescrError) Error() string {
+	if e.Op == "parse" {
+		return fmt.Sprintf("npy: could not parse data type %q: %v", e.Descr, e.Err)
+	}
+	return fmt.Sprintf("npy: could not %s array data with data type %q: %v", e.Op, e.Descr, e.Err)
+}
+
+func (e *DescrError) Unwrap() error { return e.Err }
+
+func (e *DescrError) Is(target error) bool { return target == ErrInvalidType }
+
+// decodeError describes array data that could not be decoded with a
+// supported data type, e.g. an invalid UCS-4 code point or malformed
+// pickled data.
+// Readers report decodeErrors as *PayloadErrors.
+type decodeError struct {
+	elem int   // index of the element that could not be decoded (-1 if unknown.)
+	err  error // underlying error.
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+
+func (e *decodeError) Unwrap() error { return e.err }
+
+func (e *decodeError) Is(target error) bool { return target == ErrPayload }
+
+// readError returns the typed error describing the provided error err,
+// returned while reading the array data into ptr, after n bytes of array
+// data were consumed from the underlying reader.
+// ioerr is the error returned by the underlying reader, if any.
+func (r *Reader) readError(ptr interface{}, err error, n int64, ioerr error) error {
+	var (
+		hdr *HeaderError
+		typ *TypeMismatchError
+		shp *ShapeError
+		pay *PayloadError
+		lim *LimitError
+		dec *decodeError
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &hdr), errors.As(err, &typ), errors.As(err, &shp), errors.As(err, &pay),
+		errors.As(err, &lim):
+		return err
+	case errors.As(err, &dec):
+		perr := &PayloadError{
+			Element:    -1,
+			ByteOffset: r.Header.DataOffset,
+			Err:        err,
+		}
+		if dt, e := r.dtype(); e == nil && dt.kind != 'O' && dt.esize > 0 && dec.elem >= 0 {
+			perr.Element = dec.elem
+			perr.ByteOffset += int64(dec.elem) * int64(dt.esize)
+		}
+		return perr
+	case errors.Is(err, ErrTypeMismatch), errors.Is(err, errNoConv):
+		return &TypeMismatchError{
+			OnDisk:    r.Header.Descr.Type,
+			Requested: elemType(ptr),
+			Err:       err,
+		}
+	case errors.Is(err, ErrShapeMismatch):
+		return &ShapeError{
+			Got:  r.Header.Descr.Shape,
+			Want: shapeOf(elemType(ptr)),
+			Err:  err,
+		}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		ioerr != nil && ioerr != io.EOF:
+		return r.payloadError(n, err)
+	}
+	return err
+}
+
+// payloadError returns a PayloadError for the provided error err, returned
+// after n bytes of array data were read.
+// io.EOF errors are reported as io.ErrUnexpectedEOF, as the header
+// describes more array data.
+func (r *Reader) payloadError(n int64, err error) *PayloadError {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	elem := -1
+	if dt, e := r.dtype(); e == nil && dt.kind != 'O' && dt.esize > 0 {
+		elem = int(n / int64(dt.esize))
+	}
+	return &PayloadError{
+		Element:    elem,
+		ByteOffset: r.Header.DataOffset + n,
+		Err:        err,
+	}
+}
+
+// elemType returns the type of the value pointed at by ptr.
+func elemType(ptr interface{}) reflect.Type {
+	rt := reflect.TypeOf(ptr)
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt
+}
+
+// shapeOf returns the shape of the arrays a value of type rt can hold,
+// with -1 for dimensions of any size.
+func shapeOf(rt reflect.Type) []int {
+	shape := []int{}
+	for rt != nil && (rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array) {
+		switch rt.Kind() {
+		case reflect.Slice:
+			shape = append(shape, -1)
+		case reflect.Array:
+			shape = append(shape, rt.Len())
+		}
+		rt = rt.Elem()
+	}
+	switch rt {
+	case rtDense, rtCDense:
+		shape = append(shape, -1, -1)
+	case rtVecDense:
+		shape = append(shape, -1)
+	}
+	return shape
+}
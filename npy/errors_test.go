@@ -0,0 +1,275 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := Write(buf, []float64{0, 1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatalf("could not write data: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	noShape := new(bytes.Buffer)
+	hdr := newHeader()
+	hdr.Descr.Type = "<f8"
+	err = writeHeader(noShape, hdr)
+	if err != nil {
+		t.Fatalf("could not write header: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		raw    []byte
+		offset int64
+		err    error
+	}{
+		{"magic", append([]byte("\x93NUMPZ"), raw[6:]...), 0, nil},
+		{"short-magic", raw[:3], 0, io.ErrUnexpectedEOF},
+		{"version", append(append([]byte(nil), raw[:6]...), append([]byte{3}, raw[7:]...)...), 6, nil},
+		{"short-header", raw[:20], 12, io.ErrUnexpectedEOF},
+		{"dict", bytes.Replace(noShape.Bytes(), []byte("'shape'"), []byte("'shapE'"), 1), 12, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tc.raw))
+			var herr *HeaderError
+			if !errors.As(err, &herr) {
+				t.Fatalf("invalid error type %T: %+v", err, err)
+			}
+			if got, want := herr.Offset, tc.offset; got != want {
+				t.Fatalf("invalid offset:\ngot= %d\nwant=%d", got, want)
+			}
+			if !errors.Is(err, ErrInvalidNumPyFormat) {
+				t.Fatalf("error does not match ErrInvalidNumPyFormat: %+v", err)
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.err)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(nil))
+		if err != io.EOF {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, io.EOF)
+		}
+	})
+}
+
+func TestReadErrors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteShaped(buf, []float64{0, 1, 2, 3, 4, 5}, []int{2, 3}, false)
+	if err != nil {
+		t.Fatalf("could not write data: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	hdr, err := ReadHeader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("could not read header: %+v", err)
+	}
+
+	t.Run("type-mismatch", func(t *testing.T) {
+		err := Read(bytes.NewReader(raw), new([]float32))
+		var terr *TypeMismatchError
+		if !errors.As(err, &terr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := terr.OnDisk, "<f8"; got != want {
+			t.Fatalf("invalid on-disk type:\ngot= %q\nwant=%q", got, want)
+		}
+		if got, want := terr.Requested, reflect.TypeOf([]float32(nil)); got != want {
+			t.Fatalf("invalid requested type:\ngot= %v\nwant=%v", got, want)
+		}
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("error does not match ErrTypeMismatch: %+v", err)
+		}
+	})
+
+	t.Run("shape", func(t *testing.T) {
+		err := Read(bytes.NewReader(raw), new([1][3]float64))
+		var serr *ShapeError
+		if !errors.As(err, &serr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := serr.Got, []int{2, 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+		if got, want := serr.Want, []int{1, 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid wanted shape:\ngot= %v\nwant=%v", got, want)
+		}
+		if !errors.Is(err, ErrShapeMismatch) {
+			t.Fatalf("error does not match ErrShapeMismatch: %+v", err)
+		}
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		short := raw[:hdr.DataOffset+3*8+4]
+		for _, tc := range []struct {
+			name string
+			read func(ptr any) error
+		}{
+			{"read", func(ptr any) error { return Read(bytes.NewReader(short), ptr) }},
+			{"read-at", func(ptr any) error { return ReadAt(bytes.NewReader(short), ptr) }},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.read(new([]float64))
+				var perr *PayloadError
+				if !errors.As(err, &perr) {
+					t.Fatalf("invalid error type %T: %+v", err, err)
+				}
+				if got, want := perr.Element, 3; got != want {
+					t.Fatalf("invalid element:\ngot= %d\nwant=%d", got, want)
+				}
+				if got, want := perr.ByteOffset, hdr.DataOffset+3*8+4; got != want {
+					t.Fatalf("invalid byte offset:\ngot= %d\nwant=%d", got, want)
+				}
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("error does not match io.ErrUnexpectedEOF: %+v", err)
+				}
+				if !errors.Is(err, ErrPayload) {
+					t.Fatalf("error does not match ErrPayload: %+v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("payload-eof", func(t *testing.T) {
+		// truncated at an element boundary, with an unknown data size.
+		short := io.MultiReader(bytes.NewReader(raw[:hdr.DataOffset+3*8]))
+		for _, ptr := range []any{new([]float64), new([2][3]float64), new(Array)} {
+			err := Read(short, ptr)
+			if !errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+				t.Fatalf("invalid error for %T:\ngot= %v\nwant=%v", ptr, err, io.ErrUnexpectedEOF)
+			}
+			if !errors.Is(err, ErrPayload) {
+				t.Fatalf("error for %T does not match ErrPayload: %+v", ptr, err)
+			}
+			short = io.MultiReader(bytes.NewReader(raw[:hdr.DataOffset+3*8]))
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(raw), WithMaxElements(4))
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := *lerr, (LimitError{Resource: "number of elements", Size: 6, Limit: 4}); got != want {
+			t.Fatalf("invalid limit error:\ngot= %+v\nwant=%+v", got, want)
+		}
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("error does not match ErrLimitExceeded: %+v", err)
+		}
+	})
+
+	t.Run("descr", func(t *testing.T) {
+		bad := bytes.Replace(raw, []byte("'<f8'"), []byte("'<x8'"), 1)
+		err := Read(bytes.NewReader(bad), new([]float64))
+		var derr *DescrError
+		if !errors.As(err, &derr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := derr.Descr, "<x8"; got != want {
+			t.Fatalf("invalid descr:\ngot= %q\nwant=%q", got, want)
+		}
+		if got, want := derr.Op, "parse"; got != want {
+			t.Fatalf("invalid op:\ngot= %q\nwant=%q", got, want)
+		}
+		if !errors.Is(err, ErrInvalidType) {
+			t.Fatalf("error does not match ErrInvalidType: %+v", err)
+		}
+
+		err = Write(io.Discard, &Array{descr: ArrayDescr{kind: 'u', esize: 4, order: binary.LittleEndian}, shape: []int{1}, data: []float64{1}})
+		if !errors.As(err, &derr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := derr.Op, "encode"; got != want {
+			t.Fatalf("invalid op:\ngot= %q\nwant=%q", got, want)
+		}
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		newFile := func(descr string, shape []int, payload []byte) []byte {
+			buf := new(bytes.Buffer)
+			hdr := newHeader()
+			hdr.Descr.Type = descr
+			hdr.Descr.Shape = shape
+			err := writeHeader(buf, hdr)
+			if err != nil {
+				t.Fatalf("could not write header: %+v", err)
+			}
+			buf.Write(payload)
+			return buf.Bytes()
+		}
+
+		// second element holds an invalid code point.
+		ucs4 := newFile("<U1", []int{2}, []byte{'a', 0, 0, 0, 0, 0, 0x11, 0})
+		for _, tc := range []struct {
+			name string
+			raw  []byte
+			ptr  any
+			elem int
+		}{
+			{"string-slice", ucs4, new([]string), 1},
+			{"string-array", ucs4, new(Array), 1},
+			{"pickle", newFile("|O", []int{2}, []byte("not a pickle")), new(Array), -1},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				err := Read(bytes.NewReader(tc.raw), tc.ptr)
+				var perr *PayloadError
+				if !errors.As(err, &perr) {
+					t.Fatalf("invalid error type %T: %+v", err, err)
+				}
+				if got, want := perr.Element, tc.elem; got != want {
+					t.Fatalf("invalid element:\ngot= %d\nwant=%d", got, want)
+				}
+				if !errors.Is(err, ErrPayload) {
+					t.Fatalf("error does not match ErrPayload: %+v", err)
+				}
+				if errors.Is(err, ErrInvalidType) {
+					t.Fatalf("error matches ErrInvalidType: %+v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("read-at", func(t *testing.T) {
+		err := ReadAt(bytes.NewReader(raw), new([1][3]float64))
+		var serr *ShapeError
+		if !errors.As(err, &serr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+
+		err = ReadRows(bytes.NewReader(raw), new([]float64), 1, 3)
+		if !errors.As(err, &serr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := serr.Got, []int{2, 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+	})
+
+	t.Run("decoder", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(raw[:len(raw)-4]))
+		err := dec.Decode(new([]float64))
+		var perr *PayloadError
+		if !errors.As(err, &perr) {
+			t.Fatalf("invalid error type %T: %+v", err, err)
+		}
+		if got, want := perr.Element, 5; got != want {
+			t.Fatalf("invalid element:\ngot= %d\nwant=%d", got, want)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Numeric is the set of Go types that can be read from and written to
+// NumPy data files as flat slices.
+type Numeric interface {
+	bool |
+		int8 | int16 | int32 | int64 |
+		uint8 | uint16 | uint32 | uint64 |
+		float32 | float64 |
+		complex64 | complex128
+}
+
+// ReadAs reads the numpy-array data from the r NumPy data file io.Reader,
+// and returns it as a flat slice, together with the array shape.
+// The provided options configure the underlying Reader (see NewReader.)
+//
+// The data is returned in C-order (row-major), even if the on-disk data is
+// stored in Fortran-order, unless WithLayout(RawLayout) is provided.
+// ReadAs returns an error if the on-disk data type and T don't match.
+func ReadAs[T Numeric](r io.Reader, opts ...ReadOption) (data []T, shape []int, err error) {
+	rr, err := NewReader(r, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rr.cfg.layout != RawLayout {
+		rr.cfg.layout = CLayout
+	}
+
+	err = rr.Read(&data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, rr.Header.Descr.Shape, nil
+}
+
+// WriteShaped writes the flat data slice into w in the NumPy data format,
+// as an array with the provided shape.
+//
+// If fortran is true, data is assumed to be laid out in Fortran-order
+// (column-major), and is written out as such.
+// Otherwise, data is assumed to be laid out in C-order (row-major.)
+//
+// WriteShaped returns an error if the number of elements described by
+// shape does not match the length of data.
+func WriteShaped[T Numeric](w io.Writer, data []T, shape []int, fortran bool) error {
+	for i, dim := range shape {
+		if dim < 0 {
+			return fmt.Errorf("npy: invalid shape[%d]=%d: %w", i, dim, ErrShapeMismatch)
+		}
+	}
+	if n := numElems(shape); n != len(data) {
+		return fmt.Errorf("npy: shape %v (size=%d) does not match data length %d: %w", shape, n, len(data), ErrShapeMismatch)
+	}
+
+	rv := reflect.ValueOf(data)
+	descr, err := dtypeFrom(rv, rv.Type())
+	if err != nil {
+		return err
+	}
+	dt, err := newDtype(descr)
+	if err != nil {
+		return err
+	}
+
+	hdr := newHeader()
+	hdr.Descr.Type = descr
+	hdr.Descr.Fortran = fortran
+	hdr.Descr.Shape = shape
+
+	err = writeHeader(w, hdr)
+	if err != nil {
+		return err
+	}
+
+	return writeData(w, rv, dt)
+}
+
+// fortranToC returns the Fortran-ordered data, reordered in C-order.
+func fortranToC[T any](data []T, shape []int) []T {
+	if len(shape) < 2 {
+		return data
+	}
+
+	var (
+		o       = make([]T, len(data))
+		ndim    = len(shape)
+		idx     = make([]int, ndim)
+		strides = make([]int, ndim)
+	)
+	stride := 1
+	for i, dim := range shape {
+		strides[i] = stride
+		stride *= dim
+	}
+
+	j := 0
+	for i := range o {
+		o[i] = data[j]
+		for k := ndim - 1; k >= 0; k-- {
+			idx[k]++
+			j += strides[k]
+			if idx[k] < shape[k] {
+				break
+			}
+			j -= idx[k] * strides[k]
+			idx[k] = 0
+		}
+	}
+	return o
+}
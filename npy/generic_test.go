@@ -0,0 +1,161 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadAs(t *testing.T) {
+	for _, tc := range []struct {
+		fname string
+		want  []float32
+		shape []int
+	}{
+		{
+			fname: "../testdata/data_float32_2x3_corder.npy",
+			want:  []float32{0, 1, 2, 3, 4, 5},
+			shape: []int{2, 3},
+		},
+		{
+			fname: "../testdata/data_float32_2x3_forder.npy",
+			want:  []float32{0, 2, 4, 1, 3, 5},
+			shape: []int{2, 3},
+		},
+		{
+			fname: "../testdata/data_float32_6x1_forder.npy",
+			want:  []float32{0, 1, 2, 3, 4, 5},
+			shape: []int{6, 1},
+		},
+		{
+			fname: "../testdata/data_float32_scalar_corder.npy",
+			want:  []float32{42},
+			shape: nil,
+		},
+	} {
+		t.Run(tc.fname, func(t *testing.T) {
+			f, err := os.Open(tc.fname)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			data, shape, err := ReadAs[float32](f)
+			if err != nil {
+				t.Fatalf("could not read data: %+v", err)
+			}
+
+			if got, want := data, tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+			}
+			if got, want := shape, tc.shape; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+
+	f, err := os.Open("../testdata/data_float32_2x3_corder.npy")
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	_, _, err = ReadAs[int32](f)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrTypeMismatch)
+	}
+}
+
+func TestWriteShaped(t *testing.T) {
+	data := make([]float32, 128*256)
+	for i := range data {
+		data[i] = float32(i)
+	}
+
+	for _, fortran := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		err := WriteShaped(buf, data, []int{128, 256}, fortran)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("could not create reader: %+v", err)
+		}
+		if got, want := r.Header.Descr.Type, "<f4"; got != want {
+			t.Fatalf("invalid dtype: got=%q, want=%q", got, want)
+		}
+		if got, want := r.Header.Descr.Fortran, fortran; got != want {
+			t.Fatalf("invalid fortran order: got=%v, want=%v", got, want)
+		}
+
+		var arr Array
+		err = r.Read(&arr)
+		if err != nil {
+			t.Fatalf("could not read array: %+v", err)
+		}
+		if got, want := arr.Shape(), []int{128, 256}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+
+		want := float32(1*256 + 2)
+		if fortran {
+			want = float32(1 + 2*128)
+		}
+		if got := arr.At(1, 2); got != want {
+			t.Fatalf("invalid value: got=%v, want=%v", got, want)
+		}
+
+		got, shape, err := ReadAs[float32](bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if !reflect.DeepEqual(shape, []int{128, 256}) {
+			t.Fatalf("invalid shape: %v", shape)
+		}
+		if v := got[1*256+2]; v != want {
+			t.Fatalf("invalid C-order value: got=%v, want=%v", v, want)
+		}
+
+		got, _, err = ReadAs[float32](bytes.NewReader(buf.Bytes()), WithLayout(RawLayout))
+		if err != nil {
+			t.Fatalf("could not read raw data: %+v", err)
+		}
+		idx := 1*256 + 2
+		if fortran {
+			idx = 1 + 2*128
+		}
+		if v := got[idx]; v != want {
+			t.Fatalf("invalid raw value: got=%v, want=%v", v, want)
+		}
+
+		_, _, err = ReadAs[float32](bytes.NewReader(buf.Bytes()), WithMaxElements(128))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, ErrLimitExceeded)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		data  []int16
+		shape []int
+	}{
+		{"too-short", []int16{1, 2, 3}, []int{2, 2}},
+		{"too-long", []int16{1, 2, 3, 4, 5}, []int{2, 2}},
+		{"negative", []int16{}, []int{-1, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WriteShaped(new(bytes.Buffer), tc.data, tc.shape, false)
+			if !errors.Is(err, ErrShapeMismatch) {
+				t.Fatalf("invalid error: got=%v, want=%v", err, ErrShapeMismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Layout describes how the elements of Fortran-ordered (column-major)
+// array data are laid out when read into Go slices and arrays.
+//
+// Matrices (e.g. *mat.Dense), tensors and Arrays are not affected: they
+// always honour the memory layout of the array data.
+type Layout int
+
+const (
+	// AutoLayout lays out the elements in C-order (row-major) when read
+	// into nested (N-dimensional) slices and arrays (e.g. *[][]float64 or
+	// *[2][3]int32), and as stored when read into flat slices and arrays
+	// (e.g. *[]float64 or *[6]int32).
+	AutoLayout Layout = iota
+
+	// CLayout always lays out the elements in C-order (row-major.)
+	CLayout
+
+	// RawLayout always lays out the elements as stored.
+	// The Header.Descr.Fortran field of the Reader reports whether the
+	// elements are stored in Fortran-order (column-major.)
+	RawLayout
+)
+
+// WithLayout sets how the elements of Fortran-ordered array data are laid
+// out when read into Go slices and arrays.
+// The default is AutoLayout.
+func WithLayout(layout Layout) ReadOption {
+	return func(cfg *readConfig) {
+		cfg.layout = layout
+	}
+}
+
+// readLayout reads the numeric array data into the provided nested
+// slice or array, or into the provided flat slice or array when the
+// elements need to be reordered in C-order.
+// readLayout returns false if the destination was not handled.
+func (r *Reader) readLayout(dst reflect.Value) (bool, error) {
+	depth, et := nestedElem(dst.Type())
+	if depth == 0 || !isNumericKind(et.Kind()) {
+		return false, nil
+	}
+
+	descr, err := r.dtype()
+	if err != nil || !descr.isNumeric() {
+		return false, nil
+	}
+
+	var (
+		shape   = r.Header.Descr.Shape
+		reorder = r.Header.Descr.Fortran && len(shape) > 1 && r.cfg.layout != RawLayout
+	)
+	switch {
+	case depth > 1:
+		if depth != len(shape) {
+			return true, fmt.Errorf(
+				"npy: can not read %d-dimensional array into %v: %w",
+				len(shape), dst.Type(), ErrShapeMismatch,
+			)
+		}
+	case reorder && r.cfg.layout == CLayout:
+		// flat destination.
+	default:
+		return false, nil
+	}
+
+	flat := reflect.New(reflect.SliceOf(et))
+	err = r.readValue(flat.Interface())
+	if err != nil {
+		return true, err
+	}
+
+	var (
+		src  = flat.Elem()
+		perm []int
+	)
+	if reorder {
+		perm = cOrder(shape)
+	}
+
+	if depth == 1 {
+		return true, setFlat(dst, src, perm)
+	}
+	i := 0
+	return true, setNested(dst, shape, src, perm, &i)
+}
+
+// nestedElem returns the number of nested slice and array levels of rt,
+// and the type of the innermost elements.
+func nestedElem(rt reflect.Type) (int, reflect.Type) {
+	depth := 0
+	for rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+		depth++
+		rt = rt.Elem()
+	}
+	return depth, rt
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// cOrder returns, for each element of an array with the provided shape,
+// taken in C-order, its index in the Fortran-ordered array data.
+func cOrder(shape []int) []int {
+	idx := make([]int, numElems(shape))
+	for i := range idx {
+		idx[i] = i
+	}
+	return fortranToC(idx, shape)
+}
+
+// setFlat sets the elements of the flat dst slice or array from the src
+// slice, taken in the order given by perm (if any.)
+// Like Read, non-empty slices are only filled up to their length.
+func setFlat(dst, src reflect.Value, perm []int) error {
+	n := src.Len()
+	switch dst.Kind() {
+	case reflect.Slice:
+		switch {
+		case dst.Len() == 0:
+			dst.Set(reflect.MakeSlice(dst.Type(), n, n))
+		case dst.Len() < n:
+			n = dst.Len()
+		}
+	case reflect.Array:
+		if dst.Len() < n {
+			return ErrShapeMismatch
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		j := i
+		if perm != nil {
+			j = perm[i]
+		}
+		dst.Index(i).Set(src.Index(j))
+	}
+	return nil
+}
+
+// setNested sets the elements of the nested dst slice or array, with the
+// provided shape, from the src slice, taken in the order given by perm
+// (if any), starting at the i-th element.
+func setNested(dst reflect.Value, shape []int, src reflect.Value, perm []int, i *int) error {
+	n := shape[0]
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(dst.Type(), n, n))
+	case reflect.Array:
+		if dst.Len() < n {
+			return fmt.Errorf("npy: can not read axis of size %d into %v: %w", n, dst.Type(), ErrShapeMismatch)
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		if len(shape) > 1 {
+			err := setNested(dst.Index(k), shape[1:], src, perm, i)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		j := *i
+		if perm != nil {
+			j = perm[j]
+		}
+		dst.Index(k).Set(src.Index(j))
+		*i++
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadLayout(t *testing.T) {
+	write := func(data []float64, shape []int, fortran bool) []byte {
+		buf := new(bytes.Buffer)
+		err := WriteShaped(buf, data, shape, fortran)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		return buf.Bytes()
+	}
+
+	var (
+		raw    = []float64{0, 1, 2, 3, 4, 5}
+		forder = write(raw, []int{2, 3}, true)
+		corder = write(raw, []int{2, 3}, false)
+	)
+
+	for _, tc := range []struct {
+		name   string
+		raw    []byte
+		layout Layout
+		ptr    func() any
+		want   any
+	}{
+		{"f-auto-nested", forder, AutoLayout, func() any { return new([][]float64) }, &[][]float64{{0, 2, 4}, {1, 3, 5}}},
+		{"f-auto-array", forder, AutoLayout, func() any { return new([2][3]float64) }, &[2][3]float64{{0, 2, 4}, {1, 3, 5}}},
+		{"f-auto-mixed", forder, AutoLayout, func() any { return new([][3]float64) }, &[][3]float64{{0, 2, 4}, {1, 3, 5}}},
+		{"f-auto-flat", forder, AutoLayout, func() any { return new([]float64) }, &[]float64{0, 1, 2, 3, 4, 5}},
+		{"f-auto-flat-array", forder, AutoLayout, func() any { return new([6]float64) }, &[6]float64{0, 1, 2, 3, 4, 5}},
+		{"f-c-nested", forder, CLayout, func() any { return new([][]float64) }, &[][]float64{{0, 2, 4}, {1, 3, 5}}},
+		{"f-c-flat", forder, CLayout, func() any { return new([]float64) }, &[]float64{0, 2, 4, 1, 3, 5}},
+		{"f-c-flat-array", forder, CLayout, func() any { return new([6]float64) }, &[6]float64{0, 2, 4, 1, 3, 5}},
+		{"f-c-flat-partial", forder, CLayout, func() any { v := make([]float64, 4); return &v }, &[]float64{0, 2, 4, 1}},
+		{"f-raw-nested", forder, RawLayout, func() any { return new([][]float64) }, &[][]float64{{0, 1, 2}, {3, 4, 5}}},
+		{"f-raw-flat", forder, RawLayout, func() any { return new([]float64) }, &[]float64{0, 1, 2, 3, 4, 5}},
+		{"c-auto-nested", corder, AutoLayout, func() any { return new([][]float64) }, &[][]float64{{0, 1, 2}, {3, 4, 5}}},
+		{"c-c-flat", corder, CLayout, func() any { return new([]float64) }, &[]float64{0, 1, 2, 3, 4, 5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.ptr()
+			err := Read(bytes.NewReader(tc.raw), got, WithLayout(tc.layout))
+			if err != nil {
+				t.Fatalf("could not read data: %+v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, tc.want)
+			}
+
+			got = tc.ptr()
+			err = ReadAt(bytes.NewReader(tc.raw), got, WithLayout(tc.layout))
+			if err != nil {
+				t.Fatalf("could not read data with ReadAt: %+v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid ReadAt data:\ngot= %v\nwant=%v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("3d", func(t *testing.T) {
+		data := make([]float64, 2*3*4)
+		for i := range data {
+			data[i] = float64(i)
+		}
+		raw := write(data, []int{2, 3, 4}, true)
+
+		want, _, err := ReadAs[float64](bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+
+		var got [][][]float64
+		err = Read(bytes.NewReader(raw), &got)
+		if err != nil {
+			t.Fatalf("could not read nested data: %+v", err)
+		}
+		var flat []float64
+		for _, v := range got {
+			for _, v := range v {
+				flat = append(flat, v...)
+			}
+		}
+		if !reflect.DeepEqual(flat, want) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", flat, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			ptr  any
+			err  error
+		}{
+			{"ndims", new([][][]float64), ErrShapeMismatch},
+			{"short-array", new([1][3]float64), ErrShapeMismatch},
+			{"short-inner-array", new([][2]float64), ErrShapeMismatch},
+			{"type", new([][]float32), ErrTypeMismatch},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				err := Read(bytes.NewReader(forder), tc.ptr)
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,304 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+// BigComplex is an extended precision complex number, as stored in NumPy
+// clongdouble ('c32') arrays.
+type BigComplex struct {
+	Real *big.Float
+	Imag *big.Float
+}
+
+var (
+	bigFloatType   = reflect.TypeOf((*big.Float)(nil))
+	bigComplexType = reflect.TypeOf((*BigComplex)(nil)).Elem()
+)
+
+const (
+	float80Bias = 16383   // exponent bias of the x87 extended precision format.
+	float80Inf  = 0x7fff  // biased exponent of infinities and NaNs.
+	float80NaN  = 3 << 62 // mantissa of the default (quiet) NaN.
+)
+
+// isExtended returns whether this data type is an extended precision
+// longdouble ('f16') or clongdouble ('c32') data type.
+func (dt ArrayDescr) isExtended() bool {
+	return (dt.kind == 'f' && dt.esize == 16) || (dt.kind == 'c' && dt.esize == 32)
+}
+
+// decodeExtended decodes the extended precision raw data into a
+// []*big.Float (for longdouble) or a []BigComplex (for clongdouble) slice.
+func (dt ArrayDescr) decodeExtended(raw []byte) (any, error) {
+	const sz = 16
+	var (
+		n   = len(raw) / sz
+		fs  = make([]*big.Float, n)
+		err error
+	)
+	for i := range fs {
+		fs[i], err = decodeFloat80(raw[i*sz:(i+1)*sz], dt.order)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode element %d: %w", i, err)
+		}
+	}
+
+	if dt.kind == 'f' {
+		return fs, nil
+	}
+
+	cs := make([]BigComplex, n/2)
+	for i := range cs {
+		cs[i] = BigComplex{Real: fs[2*i], Imag: fs[2*i+1]}
+	}
+	return cs, nil
+}
+
+// encodeExtended encodes the provided []*big.Float (for longdouble) or
+// []BigComplex (for clongdouble) data slice.
+func (dt ArrayDescr) encodeExtended(data any) ([]byte, error) {
+	var fs []*big.Float
+	switch data := data.(type) {
+	case []*big.Float:
+		if dt.kind != 'f' {
+			return nil, fmt.Errorf("npy: invalid data type %T for dtype %q: %w", data, dt.Descr(), ErrTypeMismatch)
+		}
+		fs = data
+	case []BigComplex:
+		if dt.kind != 'c' {
+			return nil, fmt.Errorf("npy: invalid data type %T for dtype %q: %w", data, dt.Descr(), ErrTypeMismatch)
+		}
+		fs = make([]*big.Float, 0, 2*len(data))
+		for _, c := range data {
+			fs = append(fs, c.Real, c.Imag)
+		}
+	default:
+		return nil, fmt.Errorf("npy: invalid data type %T for dtype %q: %w", data, dt.Descr(), ErrTypeMismatch)
+	}
+
+	const sz = 16
+	raw := make([]byte, len(fs)*sz)
+	for i, f := range fs {
+		err := encodeFloat80(raw[i*sz:(i+1)*sz], f, dt.order)
+		if err != nil {
+			return nil, fmt.Errorf("npy: could not encode element %d: %w", i, err)
+		}
+	}
+	return raw, nil
+}
+
+// decodeFloat80 decodes a 16 bytes longdouble, stored in the x87 80-bit
+// extended precision format (as on x86-64 Linux), padded to 16 bytes.
+// NaNs, which can not be represented as a big.Float, are decoded as nil.
+//
+// decodeFloat80 returns an error for values that are not valid in the x87
+// format, e.g. longdoubles stored in the IEEE 754 binary128 format (as on
+// aarch64 or ppc64le Linux.)
+func decodeFloat80(p []byte, order binary.ByteOrder) (*big.Float, error) {
+	var b [16]byte
+	copy(b[:], p)
+	if isBigEndian(order) {
+		// NumPy swaps all the 16 bytes of the value.
+		reverse(b[:])
+	}
+
+	var (
+		mant = binary.LittleEndian.Uint64(b[0:8])
+		se   = binary.LittleEndian.Uint16(b[8:10])
+		neg  = se&0x8000 != 0
+		exp  = int(se & 0x7fff)
+		f    = new(big.Float).SetPrec(64)
+	)
+
+	switch {
+	case exp != 0 && mant>>63 == 0:
+		// the explicit integer bit of normal numbers, infinities and NaNs
+		// is set in the x87 format.
+		return nil, fmt.Errorf("npy: invalid x87 extended precision value (not an x86-64 longdouble?): %w", ErrInvalidType)
+	case exp == float80Inf && mant<<1 == 0:
+		f.SetInf(neg)
+		return f, nil
+	case exp == float80Inf:
+		return nil, nil
+	case exp == 0:
+		// denormals.
+		exp = 1
+	}
+
+	f.SetUint64(mant)
+	f.SetMantExp(f, exp-float80Bias-63)
+	if neg {
+		f.Neg(f)
+	}
+	return f, nil
+}
+
+// encodeFloat80 encodes f into p, in the x87 80-bit extended precision
+// format, padded to 16 bytes.
+// A nil f is encoded as a NaN.
+// encodeFloat80 returns an error if f is too large to be represented.
+func encodeFloat80(p []byte, f *big.Float, order binary.ByteOrder) error {
+	var (
+		b    [16]byte
+		mant uint64
+		exp  int
+	)
+
+	switch {
+	case f == nil:
+		mant = float80NaN
+		exp = float80Inf
+	case f.IsInf():
+		mant = 1 << 63
+		exp = float80Inf
+	case f.Sign() == 0:
+		// zero.
+	default:
+		// f = m * 2**e, with 0.5 <= |m| < 1.
+		m := new(big.Float)
+		e := f.MantExp(m)
+		m.Abs(m)
+
+		exp = e - 1 + float80Bias
+		bits := 64
+		if exp <= 0 {
+			// denormals have fewer significant bits.
+			bits = 63 + exp
+			exp = 0
+		}
+
+		// round m to the available number of significant bits.
+		r := new(big.Float)
+		switch {
+		case bits > 0:
+			r.SetMode(big.ToNearestEven).SetPrec(uint(bits)).Set(m)
+		case bits == 0 && m.Cmp(big.NewFloat(0.5)) > 0:
+			r.SetInt64(1)
+		}
+
+		switch {
+		case r.Cmp(big.NewFloat(1)) == 0 && bits == 64:
+			// rounding carried over to the next exponent.
+			mant = 1 << 63
+			exp++
+		default:
+			if bits > 0 {
+				r.SetMantExp(r, bits)
+			}
+			mant, _ = r.Uint64()
+			if exp == 0 && mant>>63 != 0 {
+				// rounding carried over to the smallest normal number.
+				exp = 1
+			}
+		}
+
+		if exp >= float80Inf {
+			return fmt.Errorf("npy: big.Float %v overflows longdouble", f)
+		}
+	}
+
+	binary.LittleEndian.PutUint64(b[0:8], mant)
+	se := uint16(exp)
+	if f != nil && f.Signbit() {
+		se |= 0x8000
+	}
+	binary.LittleEndian.PutUint16(b[8:10], se)
+
+	if isBigEndian(order) {
+		reverse(b[:])
+	}
+	copy(p, b[:])
+	return nil
+}
+
+// isBigEndian returns whether the provided byte order is big-endian.
+func isBigEndian(order binary.ByteOrder) bool {
+	if order == nil {
+		return false
+	}
+	var b [2]byte
+	order.PutUint16(b[:], 1)
+	return b[1] == 1
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+// readExtended reads the extended precision array data into the provided
+// *big.Float, *[]*big.Float, *BigComplex or *[]BigComplex value.
+func (r *Reader) readExtended(ptr any, nelems int) error {
+	kind := byte('f')
+	switch ptr.(type) {
+	case *BigComplex, *[]BigComplex:
+		kind = 'c'
+	}
+
+	descr, err := r.dtype()
+	if err != nil || !descr.isExtended() || descr.kind != kind {
+		return ErrTypeMismatch
+	}
+
+	switch ptr.(type) {
+	case *big.Float, *BigComplex:
+		if nelems != 1 {
+			return fmt.Errorf("npy: can not read %d elements into %T: %w", nelems, ptr, ErrShapeMismatch)
+		}
+	}
+
+	raw, err := r.readPayload(nelems, descr)
+	if err != nil {
+		r.err = err
+		return r.err
+	}
+	data, err := descr.decodeExtended(raw)
+	if err != nil {
+		r.err = fmt.Errorf("npy: could not decode array data: %w", err)
+		return r.err
+	}
+
+	switch ptr := ptr.(type) {
+	case *big.Float:
+		f := data.([]*big.Float)[0]
+		if f == nil {
+			r.err = ErrLongDoubleNaN
+			return r.err
+		}
+		ptr.Set(f)
+	case *[]*big.Float:
+		*ptr = data.([]*big.Float)
+	case *BigComplex:
+		*ptr = data.([]BigComplex)[0]
+	case *[]BigComplex:
+		*ptr = data.([]BigComplex)
+	}
+	return nil
+}
+
+// writeExtended writes the provided []*big.Float or []BigComplex data
+// into w, as a longdouble ('<f16') or clongdouble ('<c32') array with the
+// provided shape.
+func writeExtended(w io.Writer, data any, shape []int) error {
+	descr := ArrayDescr{kind: 'f', order: binary.LittleEndian, esize: 16, align: 16}
+	if _, ok := data.([]BigComplex); ok {
+		descr.kind = 'c'
+		descr.esize = 32
+	}
+
+	arr, err := NewArray(descr, shape, data)
+	if err != nil {
+		return err
+	}
+	return writeArray(w, *arr)
+}
@@ -0,0 +1,325 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestFloat80(t *testing.T) {
+	f80 := func(se uint16, mant uint64) []byte {
+		o := make([]byte, 16)
+		binary.LittleEndian.PutUint64(o[0:8], mant)
+		binary.LittleEndian.PutUint16(o[8:10], se)
+		return o
+	}
+	pi, _, err := big.ParseFloat("3.14159265358979323846264338327950288419716939937510582097494459", 10, 256, big.ToNearestEven)
+	if err != nil {
+		t.Fatalf("could not parse pi: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		f    *big.Float
+		raw  []byte
+	}{
+		{"zero", big.NewFloat(0), f80(0, 0)},
+		{"neg-zero", big.NewFloat(math.Copysign(0, -1)), f80(0x8000, 0)},
+		{"one", big.NewFloat(1), f80(0x3fff, 1<<63)},
+		{"neg-2.5", big.NewFloat(-2.5), f80(0xc000, 0xa<<60)},
+		{"pi", pi, f80(0x4000, 0xc90fdaa22168c235)},
+		{"inf", new(big.Float).SetInf(false), f80(0x7fff, 1<<63)},
+		{"neg-inf", new(big.Float).SetInf(true), f80(0xffff, 1<<63)},
+		{"max", new(big.Float).SetMantExp(new(big.Float).SetUint64(math.MaxUint64), 16384-64), f80(0x7ffe, math.MaxUint64)},
+		{"min-normal", new(big.Float).SetMantExp(big.NewFloat(1), -16382), f80(0x0001, 1<<63)},
+		{"min-denormal", new(big.Float).SetMantExp(big.NewFloat(1), -16445), f80(0x0000, 1)},
+		{"denormal", new(big.Float).SetMantExp(big.NewFloat(3), -16400), f80(0x0000, 3<<45)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+				want := append([]byte(nil), tc.raw...)
+				if order == binary.BigEndian {
+					reverse(want)
+				}
+
+				raw := make([]byte, 16)
+				err := encodeFloat80(raw, tc.f, order)
+				if err != nil {
+					t.Fatalf("could not encode %v (%v): %+v", tc.f, order, err)
+				}
+				if !bytes.Equal(raw, want) {
+					t.Fatalf("invalid encoding (%v):\ngot= %x\nwant=%x", order, raw, want)
+				}
+
+				got, err := decodeFloat80(raw, order)
+				if err != nil {
+					t.Fatalf("could not decode %v (%v): %+v", tc.f, order, err)
+				}
+				ref := new(big.Float).SetPrec(64).Set(tc.f)
+				if got.Cmp(ref) != 0 || got.Signbit() != ref.Signbit() {
+					t.Fatalf("invalid decoding (%v):\ngot= %v\nwant=%v", order, got, ref)
+				}
+			}
+		})
+	}
+
+	t.Run("rounding", func(t *testing.T) {
+		// 1 + 2**-64 rounds to 1 (ties to even), 1 + 3*2**-65 rounds up.
+		for _, tc := range []struct {
+			f    *big.Float
+			mant uint64
+		}{
+			{new(big.Float).SetPrec(128).Add(big.NewFloat(1), new(big.Float).SetMantExp(big.NewFloat(1), -64)), 1 << 63},
+			{new(big.Float).SetPrec(128).Add(big.NewFloat(1), new(big.Float).SetMantExp(big.NewFloat(3), -65)), 1<<63 | 1},
+		} {
+			raw := make([]byte, 16)
+			err := encodeFloat80(raw, tc.f, binary.LittleEndian)
+			if err != nil {
+				t.Fatalf("could not encode %v: %+v", tc.f, err)
+			}
+			if got, want := binary.LittleEndian.Uint64(raw), tc.mant; got != want {
+				t.Fatalf("invalid mantissa for %v:\ngot= %#x\nwant=%#x", tc.f, got, want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		raw := make([]byte, 16)
+		err := encodeFloat80(raw, new(big.Float).SetMantExp(big.NewFloat(1), 16384), binary.LittleEndian)
+		if err == nil {
+			t.Fatalf("expected an overflow error")
+		}
+		for _, raw := range [][]byte{
+			f80(0x3fff, 1<<62), // unnormal.
+			f80(0x7fff, 0),     // pseudo-infinity.
+		} {
+			_, err = decodeFloat80(raw, binary.LittleEndian)
+			if !errors.Is(err, ErrInvalidType) {
+				t.Fatalf("invalid error for %x:\ngot= %v\nwant=%v", raw, err, ErrInvalidType)
+			}
+		}
+	})
+
+	t.Run("nan", func(t *testing.T) {
+		raw := make([]byte, 16)
+		err := encodeFloat80(raw, nil, binary.LittleEndian)
+		if err != nil {
+			t.Fatalf("could not encode NaN: %+v", err)
+		}
+		if got, want := raw, f80(0x7fff, 3<<62); !bytes.Equal(got, want) {
+			t.Fatalf("invalid NaN encoding:\ngot= %x\nwant=%x", got, want)
+		}
+		for _, raw := range [][]byte{
+			f80(0x7fff, 3<<62),
+			f80(0xffff, 1<<63|1),
+		} {
+			f, err := decodeFloat80(raw, binary.LittleEndian)
+			if err != nil {
+				t.Fatalf("could not decode NaN %x: %+v", raw, err)
+			}
+			if f != nil {
+				t.Fatalf("invalid NaN value for %x: %v", raw, f)
+			}
+		}
+	})
+}
+
+func TestReadWriteExtended(t *testing.T) {
+	fs := []*big.Float{big.NewFloat(1), big.NewFloat(-2.5), big.NewFloat(1e300)}
+	cs := []BigComplex{
+		{Real: big.NewFloat(1), Imag: big.NewFloat(-1)},
+		{Real: big.NewFloat(0.5), Imag: big.NewFloat(2)},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		val   any
+		descr string
+		shape []int
+		ptr   func() any
+		want  any
+	}{
+		{
+			name:  "float",
+			val:   big.NewFloat(-2.5),
+			descr: "<f16",
+			ptr:   func() any { return new(big.Float) },
+			want:  big.NewFloat(-2.5),
+		},
+		{
+			name:  "floats",
+			val:   fs,
+			descr: "<f16",
+			shape: []int{3},
+			ptr:   func() any { return new([]*big.Float) },
+			want:  &fs,
+		},
+		{
+			name:  "complex",
+			val:   cs[1],
+			descr: "<c32",
+			ptr:   func() any { return new(BigComplex) },
+			want:  &cs[1],
+		},
+		{
+			name:  "complexes",
+			val:   cs,
+			descr: "<c32",
+			shape: []int{2},
+			ptr:   func() any { return new([]BigComplex) },
+			want:  &cs,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := Write(buf, tc.val)
+			if err != nil {
+				t.Fatalf("could not write data: %+v", err)
+			}
+			raw := buf.Bytes()
+
+			hdr, err := ReadHeader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("could not read header: %+v", err)
+			}
+			if got, want := hdr.Descr.Type, tc.descr; got != want {
+				t.Fatalf("invalid descr:\ngot= %q\nwant=%q", got, want)
+			}
+			if got, want := hdr.Descr.Shape, tc.shape; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+
+			ptr := tc.ptr()
+			err = Read(bytes.NewReader(raw), ptr)
+			if err != nil {
+				t.Fatalf("could not read data: %+v", err)
+			}
+			if got, want := bigString(ptr), bigString(tc.want); got != want {
+				t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+			}
+
+			var arr Array
+			err = Read(bytes.NewReader(raw), &arr)
+			if err != nil {
+				t.Fatalf("could not read array: %+v", err)
+			}
+			out := new(bytes.Buffer)
+			err = Write(out, &arr)
+			if err != nil {
+				t.Fatalf("could not write array: %+v", err)
+			}
+			if !bytes.Equal(out.Bytes(), raw) {
+				t.Fatalf("invalid array round-trip:\ngot= %x\nwant=%x", out.Bytes(), raw)
+			}
+
+			var f64 []float64
+			err = Read(bytes.NewReader(raw), &f64)
+			if !errors.Is(err, ErrTypeMismatch) {
+				t.Fatalf("invalid error reading []float64:\ngot= %v\nwant=%v", err, ErrTypeMismatch)
+			}
+		})
+	}
+
+	t.Run("nan", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := Write(buf, []*big.Float{big.NewFloat(1), nil, big.NewFloat(2)})
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		raw := buf.Bytes()
+
+		var got []*big.Float
+		err = Read(bytes.NewReader(raw), &got)
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if len(got) != 3 || got[0].Cmp(big.NewFloat(1)) != 0 || got[1] != nil || got[2].Cmp(big.NewFloat(2)) != 0 {
+			t.Fatalf("invalid data: %v", got)
+		}
+
+		buf.Reset()
+		err = Write(buf, []BigComplex{{Real: big.NewFloat(1), Imag: nil}})
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		var cs []BigComplex
+		err = Read(buf, &cs)
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if len(cs) != 1 || cs[0].Real.Cmp(big.NewFloat(1)) != 0 || cs[0].Imag != nil {
+			t.Fatalf("invalid data: %v", cs)
+		}
+
+		buf.Reset()
+		err = Write(buf, []*big.Float{nil})
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		err = Read(buf, new(big.Float))
+		if !errors.Is(err, ErrLongDoubleNaN) {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrLongDoubleNaN)
+		}
+	})
+
+	t.Run("scalar-dims", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := Write(buf, fs)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		err = Read(buf, new(big.Float))
+		if !errors.Is(err, ErrShapeMismatch) {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrShapeMismatch)
+		}
+	})
+
+	t.Run("descr", func(t *testing.T) {
+		for _, tc := range []struct {
+			descr string
+			want  string
+		}{
+			{"g", "<f16"},
+			{"longdouble", "<f16"},
+			{">f16", ">f16"},
+			{"G", "<c32"},
+			{"clongdouble", "<c32"},
+		} {
+			dt, err := ParseDescr(tc.descr)
+			if err != nil {
+				t.Fatalf("could not parse %q: %+v", tc.descr, err)
+			}
+			if got, want := dt.Descr(), tc.want; got != want {
+				t.Fatalf("invalid descr for %q:\ngot= %q\nwant=%q", tc.descr, got, want)
+			}
+		}
+	})
+}
+
+func bigString(v any) string {
+	var o []string
+	switch v := v.(type) {
+	case *big.Float:
+		o = append(o, v.Text('g', 20))
+	case *[]*big.Float:
+		for _, f := range *v {
+			o = append(o, f.Text('g', 20))
+		}
+	case *BigComplex:
+		o = append(o, v.Real.Text('g', 20), v.Imag.Text('g', 20))
+	case *[]BigComplex:
+		for _, c := range *v {
+			o = append(o, c.Real.Text('g', 20), c.Imag.Text('g', 20))
+		}
+	}
+	return fmt.Sprint(o)
+}
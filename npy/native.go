@@ -0,0 +1,346 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	py "github.com/nlpodyssey/gopickle/types"
+	"github.com/sbinet/npyio/npy/float16"
+)
+
+// Native returns the array's data, converted to native Go values.
+//
+// Values unpickled from object arrays (dtype '|O') are recursively converted:
+//   - python lists and tuples are converted to []any,
+//   - python sets are converted to []any, in no particular order,
+//   - python dicts are converted to map[string]any (or map[any]any if
+//     some of the keys are not strings),
+//   - python bytearrays are converted to []byte,
+//   - nested numpy arrays are converted to *Array, holding native data.
+//
+// Data of non-object arrays is returned as-is.
+func (arr Array) Native() (any, error) {
+	return toNative(arr.data)
+}
+
+func toNative(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+
+	case *py.List:
+		if v == nil {
+			return []any(nil), nil
+		}
+		return toNativeSlice(*v)
+
+	case *py.Tuple:
+		if v == nil {
+			return []any(nil), nil
+		}
+		return toNativeSlice(*v)
+
+	case *py.Set:
+		if v == nil {
+			return []any(nil), nil
+		}
+		keys := make([]any, 0, len(*v))
+		for k := range *v {
+			keys = append(keys, k)
+		}
+		return toNativeSlice(keys)
+
+	case *py.FrozenSet:
+		if v == nil {
+			return []any(nil), nil
+		}
+		keys := make([]any, 0, len(*v))
+		for k := range *v {
+			keys = append(keys, k)
+		}
+		return toNativeSlice(keys)
+
+	case *py.ByteArray:
+		if v == nil {
+			return []byte(nil), nil
+		}
+		return []byte(*v), nil
+
+	case *py.Dict:
+		if v == nil {
+			return map[string]any(nil), nil
+		}
+		keys := make([]any, len(*v))
+		vals := make([]any, len(*v))
+		for i, e := range *v {
+			keys[i] = e.Key
+			vals[i] = e.Value
+		}
+		return toNativeMap(keys, vals)
+
+	case *py.OrderedDict:
+		if v == nil {
+			return map[string]any(nil), nil
+		}
+		keys := make([]any, 0, v.Len())
+		vals := make([]any, 0, v.Len())
+		for e := v.List.Front(); e != nil; e = e.Next() {
+			kv := e.Value.(*py.OrderedDictEntry)
+			keys = append(keys, kv.Key)
+			vals = append(vals, kv.Value)
+		}
+		return toNativeMap(keys, vals)
+
+	case *Array:
+		if v == nil {
+			return v, nil
+		}
+		data, err := toNative(v.data)
+		if err != nil {
+			return nil, err
+		}
+		arr := *v
+		arr.data = data
+		return &arr, nil
+
+	case []any:
+		return toNativeSlice(v)
+
+	default:
+		return v, nil
+	}
+}
+
+func toNativeSlice(vs []any) ([]any, error) {
+	o := make([]any, len(vs))
+	for i, v := range vs {
+		v, err := toNative(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert element %d: %w", i, err)
+		}
+		o[i] = v
+	}
+	return o, nil
+}
+
+func toNativeMap(keys, vals []any) (any, error) {
+	strs := true
+	for _, k := range keys {
+		if _, ok := k.(string); !ok {
+			strs = false
+			break
+		}
+	}
+
+	if strs {
+		o := make(map[string]any, len(keys))
+		for i, k := range keys {
+			v, err := toNative(vals[i])
+			if err != nil {
+				return nil, fmt.Errorf("could not convert value for key %q: %w", k, err)
+			}
+			o[k.(string)] = v
+		}
+		return o, nil
+	}
+
+	o := make(map[any]any, len(keys))
+	for i, k := range keys {
+		k, err := toNative(k)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert key %v: %w", k, err)
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, fmt.Errorf("invalid unhashable key type %T", k)
+		}
+		v, err := toNative(vals[i])
+		if err != nil {
+			return nil, fmt.Errorf("could not convert value for key %v: %w", k, err)
+		}
+		o[k] = v
+	}
+	return o, nil
+}
+
+// decodeNative stores the native Go value src into dst.
+func decodeNative(dst reflect.Value, src any) error {
+	if arr, ok := src.(*Array); ok && arr != nil {
+		src = arr.data
+	}
+
+	if dst.Kind() == reflect.Interface {
+		switch src {
+		case nil:
+			dst.Set(reflect.Zero(dst.Type()))
+		default:
+			rv := reflect.ValueOf(src)
+			if !rv.Type().AssignableTo(dst.Type()) {
+				return fmt.Errorf("npy: can not assign %T to %v: %w", src, dst.Type(), ErrTypeMismatch)
+			}
+			dst.Set(rv)
+		}
+		return nil
+	}
+
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("npy: can not decode %T into %v: %w", src, dst.Type(), ErrTypeMismatch)
+	}
+
+	rsrc := reflect.ValueOf(src)
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeNative(dst.Elem(), src)
+
+	case reflect.Slice:
+		if str, ok := src.(string); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(str))
+			return nil
+		}
+		if rsrc.Kind() != reflect.Slice && rsrc.Kind() != reflect.Array {
+			return mismatch()
+		}
+		n := rsrc.Len()
+		sli := reflect.MakeSlice(dst.Type(), n, n)
+		for i := 0; i < n; i++ {
+			err := decodeNative(sli.Index(i), rsrc.Index(i).Interface())
+			if err != nil {
+				return fmt.Errorf("npy: could not decode element %d: %w", i, err)
+			}
+		}
+		dst.Set(sli)
+		return nil
+
+	case reflect.Array:
+		if rsrc.Kind() != reflect.Slice && rsrc.Kind() != reflect.Array {
+			return mismatch()
+		}
+		n := rsrc.Len()
+		if n > dst.Len() {
+			return ErrShapeMismatch
+		}
+		for i := 0; i < n; i++ {
+			err := decodeNative(dst.Index(i), rsrc.Index(i).Interface())
+			if err != nil {
+				return fmt.Errorf("npy: could not decode element %d: %w", i, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if rsrc.Kind() != reflect.Map {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), rsrc.Len())
+		iter := rsrc.MapRange()
+		for iter.Next() {
+			k := reflect.New(dst.Type().Key()).Elem()
+			err := decodeNative(k, iter.Key().Interface())
+			if err != nil {
+				return fmt.Errorf("npy: could not decode map key %v: %w", iter.Key(), err)
+			}
+			v := reflect.New(dst.Type().Elem()).Elem()
+			err = decodeNative(v, iter.Value().Interface())
+			if err != nil {
+				return fmt.Errorf("npy: could not decode map value for key %v: %w", iter.Key(), err)
+			}
+			m.SetMapIndex(k, v)
+		}
+		dst.Set(m)
+		return nil
+
+	case reflect.String:
+		switch src := src.(type) {
+		case string:
+			dst.SetString(src)
+		case []byte:
+			dst.SetString(string(src))
+		default:
+			return mismatch()
+		}
+		return nil
+
+	case reflect.Bool:
+		v, ok := src.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(v)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, ok := nativeInt(src)
+		if !ok || !v.IsInt64() || dst.OverflowInt(v.Int64()) {
+			return mismatch()
+		}
+		dst.SetInt(v.Int64())
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, ok := nativeInt(src)
+		if !ok || !v.IsUint64() || dst.OverflowUint(v.Uint64()) {
+			return mismatch()
+		}
+		dst.SetUint(v.Uint64())
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch src := src.(type) {
+		case float16.Num:
+			dst.SetFloat(float64(src.Float32()))
+			return nil
+		case float32, float64:
+			dst.SetFloat(rsrc.Float())
+			return nil
+		}
+		v, ok := nativeInt(src)
+		if !ok {
+			return mismatch()
+		}
+		f, _ := new(big.Float).SetInt(v).Float64()
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.Complex64, reflect.Complex128:
+		switch rsrc.Kind() {
+		case reflect.Complex64, reflect.Complex128:
+			dst.SetComplex(rsrc.Complex())
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetComplex(complex(rsrc.Float(), 0))
+			return nil
+		}
+		return mismatch()
+	}
+
+	return mismatch()
+}
+
+// nativeInt returns the integer value held by v, if any.
+func nativeInt(v any) (*big.Int, bool) {
+	switch v := v.(type) {
+	case *big.Int:
+		return v, v != nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	}
+	return nil, false
+}
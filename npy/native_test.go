@@ -0,0 +1,101 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"reflect"
+	"testing"
+
+	py "github.com/nlpodyssey/gopickle/types"
+)
+
+func TestArrayNative(t *testing.T) {
+	dict := py.NewDict()
+	dict.Set("a", pylist(1, 2))
+	dict.Set("b", py.NewTupleFromSlice([]any{"x", 1.5}))
+
+	mixed := py.NewDict()
+	mixed.Set(1, "one")
+	mixed.Set("two", 2)
+
+	odict := py.NewOrderedDict()
+	odict.Set("k", py.NewTupleFromSlice(nil))
+
+	bytes := py.ByteArray("raw")
+
+	sub := &Array{
+		descr: ArrayDescr{kind: 'O', esize: 8, align: 8, flags: 63},
+		shape: []int{1},
+		data:  pylist(py.NewTupleFromSlice([]any{1, 2})),
+	}
+
+	for _, tc := range []struct {
+		name string
+		data any
+		want any
+	}{
+		{
+			name: "float64",
+			data: []float64{1, 2, 3},
+			want: []float64{1, 2, 3},
+		},
+		{
+			name: "list",
+			data: pylist(1, "two", pylist(3.0, nil)),
+			want: []any{1, "two", []any{3.0, nil}},
+		},
+		{
+			name: "dict",
+			data: pylist(dict),
+			want: []any{map[string]any{"a": []any{1, 2}, "b": []any{"x", 1.5}}},
+		},
+		{
+			name: "mixed-dict",
+			data: pylist(mixed),
+			want: []any{map[any]any{1: "one", "two": 2}},
+		},
+		{
+			name: "ordered-dict",
+			data: pylist(odict),
+			want: []any{map[string]any{"k": []any{}}},
+		},
+		{
+			name: "bytearray",
+			data: pylist(&bytes),
+			want: []any{[]byte("raw")},
+		},
+		{
+			name: "array",
+			data: pylist(sub),
+			want: []any{&Array{
+				descr: sub.descr,
+				shape: sub.shape,
+				data:  []any{[]any{1, 2}},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			arr := Array{data: tc.data}
+			got, err := arr.Native()
+			if err != nil {
+				t.Fatalf("could not convert to native: %+v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid native value:\ngot= %#v\nwant=%#v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("unhashable", func(t *testing.T) {
+		dict := py.NewDict()
+		dict.Set(py.NewTupleFromSlice([]any{1}), 1)
+		dict.Set(2, 2)
+		arr := Array{data: pylist(dict)}
+		_, err := arr.Native()
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
@@ -8,13 +8,26 @@
 //
 // # Supported types
 //
-// npy supports r/w of scalars, arrays, slices and gonum/mat.Dense.
+// npy supports r/w of scalars, arrays, slices and gonum/mat types
+// (mat.Dense, mat.VecDense, mat.CDense and []mat.Dense; any mat.Matrix can
+// be written.)
 // Supported scalars are:
 //   - bool,
 //   - (u)int{8,16,32,64},
 //   - float{32,64},
 //   - complex{64,128}
 //
+// Extended precision longdouble ('<f16') and clongdouble ('<c32') values
+// are read and written as *big.Float and BigComplex values.
+// Byte-string ('S') and unicode ('U') values are read and written as
+// strings, and opaque ('V') values as []byte.
+//
+// N-dimensional numpy-arrays are supported through:
+//   - Array, for any data type (object arrays can only be read),
+//   - Tensor[T], for numeric data types,
+//   - nested slices and arrays (e.g. [][]float64 or [2][3]int32), which
+//     can only be read.
+//
 // # Reading
 //
 // Reading from a NumPy data file can be performed like so:
@@ -35,6 +48,13 @@
 //	var data uint64
 //	err = npy.Read(f, &data)
 //
+// The memory allocated for the array data is always checked against the
+// size of the input, when it is known (e.g. files and in-memory buffers.)
+// Programs reading untrusted input can further bound the decoding with
+// ReadOptions:
+//
+//	err = npy.Read(f, &data, npy.WithMaxElements(1<<20), npy.WithMaxBytes(64<<20))
+//
 // # Writing
 //
 // Writing into a NumPy data file can be done like so:
@@ -53,28 +73,75 @@
 //
 //	var data [42]complex128 = ...
 //	err = npy.Write(f, data)
+//
+// Strings are written out as unicode ('U') arrays and [][]byte values as
+// byte-string ('S') arrays. WriteOptions control the string data type:
+//
+//	err = npy.Write(f, []string{"a", "b"}, npy.WithStringKind('S'), npy.WithStringWidth(8))
+//
+// Arrays are written out with their data type, shape and memory layout,
+// so any NumPy data file can be read back and written out unchanged:
+//
+//	var arr npy.Array
+//	err = npy.Read(r, &arr)
+//	err = npy.Write(w, &arr)
+//
+// # Large arrays
+//
+// ReadAt and WriteAt read and write very large numeric arrays from an
+// io.ReaderAt and into an io.WriterAt (e.g. an *os.File), splitting the
+// array data into chunks that are decoded and encoded concurrently:
+//
+//	var arr npy.Array
+//	err = npy.ReadAt(f, &arr, npy.WithReadWorkers(8))
+//	err = npy.WriteAt(f, &arr, npy.WithWriteWorkers(8))
+//
+// ReadRows reads only a range of rows (sub-arrays along the first axis)
+// of a C-ordered array from an io.ReaderAt:
+//
+//	var rows []float64
+//	err = npy.ReadRows(f, &rows, 100, 200)
+//
+// # Streams
+//
+// Encoder and Decoder write and read consecutive arrays over a single
+// stream (e.g. a pipe or a net.Conn):
+//
+//	enc := npy.NewEncoder(conn)
+//	err = enc.Encode(xs)
+//	err = enc.Encode(ys)
+//
+//	dec := npy.NewDecoder(conn)
+//	err = dec.Decode(&xs)
+//	err = dec.Decode(&ys)
 package npy
 
 import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+
+	"github.com/sbinet/npyio/npy/float16"
 )
 
 var (
 	errNilPtr = errors.New("npy: nil pointer")
 	errNotPtr = errors.New("npy: expected a pointer to a value")
-	errDims   = errors.New("npy: invalid dimensions")
 	errNoConv = errors.New("npy: no legal type conversion")
 
 	// ErrInvalidNumPyFormat is the error returned by NewReader when
 	// the underlying io.Reader is not a valid or recognized NumPy data
 	// file format.
+	// NewReader returns a *HeaderError, that satisfies
+	// errors.Is(err, ErrInvalidNumPyFormat).
 	ErrInvalidNumPyFormat = errors.New("npy: not a valid NumPy file format")
 
 	// ErrTypeMismatch is the error returned by Reader when the on-disk
 	// data type and the user provided one do NOT match.
+	// Reader returns a *TypeMismatchError, that satisfies
+	// errors.Is(err, ErrTypeMismatch).
 	ErrTypeMismatch = errors.New("npy: types don't match")
 
 	// ErrInvalidType is the error returned by Reader and Writer when
@@ -82,6 +149,34 @@ var (
 	// reliably (de)serialized.
 	ErrInvalidType = errors.New("npy: invalid or unsupported type")
 
+	// ErrShapeMismatch is the error returned when an array shape is
+	// invalid, or when it does not match the shape of the user provided
+	// Go value.
+	// Reader returns a *ShapeError, that satisfies
+	// errors.Is(err, ErrShapeMismatch).
+	ErrShapeMismatch = errors.New("npy: invalid dimensions")
+
+	// ErrPayload is the error returned by Reader when the array data of a
+	// NumPy data file can not be read, e.g. because the file is truncated
+	// or corrupt.
+	// Reader returns a *PayloadError, that satisfies
+	// errors.Is(err, ErrPayload).
+	// Truncated array data also satisfies errors.Is(err, io.ErrUnexpectedEOF).
+	ErrPayload = errors.New("npy: could not read array data")
+
+	// ErrLimitExceeded is the error returned by Reader when the header
+	// or the array data of a NumPy data file exceed the configured
+	// resource limits (see WithMaxHeaderBytes, WithMaxElements and
+	// WithMaxBytes), or when the size of the array data overflows.
+	// Reader returns a *LimitError, that satisfies
+	// errors.Is(err, ErrLimitExceeded).
+	ErrLimitExceeded = errors.New("npy: resource limit exceeded")
+
+	// ErrLongDoubleNaN is the error returned by Reader when a NaN
+	// extended precision (longdouble) value is read into a *big.Float,
+	// that can not represent it.
+	ErrLongDoubleNaN = errors.New("npy: NaN longdouble can not be represented as a big.Float")
+
 	// Magic header present at the start of a NumPy data file format.
 	// See https://numpy.org/neps/nep-0001-npy-format.html
 	Magic = [6]byte{'\x93', 'N', 'U', 'M', 'P', 'Y'}
@@ -96,6 +191,11 @@ type Header struct {
 		Fortran bool   // whether the array data is stored in Fortran-order (col-major)
 		Shape   []int  // array shape (e.g. [2,3] a 2-rows, 3-cols array
 	}
+
+	// DataOffset is the offset, in bytes, of the array data from the
+	// start of the NumPy data file.
+	// DataOffset is only set for headers read from a NumPy data file.
+	DataOffset int64
 }
 
 // newHeader creates a new Header with the major/minor version numbers that
@@ -107,6 +207,51 @@ func newHeader() Header {
 	}
 }
 
+// Dtype returns the data type of the array elements.
+func (h Header) Dtype() (*ArrayDescr, error) {
+	dt, err := ParseDescr(h.Descr.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &dt, nil
+}
+
+// Len returns the number of elements of the array.
+func (h Header) Len() int {
+	return numElems(h.Descr.Shape)
+}
+
+// ItemSize returns the size, in bytes, of an array element.
+func (h Header) ItemSize() (int, error) {
+	dt, err := h.Dtype()
+	if err != nil {
+		return 0, err
+	}
+	return dt.ItemSize(), nil
+}
+
+// DataSize returns the size, in bytes, of the array data.
+//
+// DataSize returns an error for arrays of python objects, as their data
+// is stored as a python pickle, whose size is not known in advance.
+func (h Header) DataSize() (int64, error) {
+	dt, err := h.Dtype()
+	if err != nil {
+		return 0, err
+	}
+	if dt.kind == 'O' {
+		return 0, fmt.Errorf("npy: data size of object arrays is not known: %w", ErrInvalidType)
+	}
+	n, err := shapeSize(h.Descr.Shape)
+	if err != nil {
+		return 0, err
+	}
+	if esize := int64(dt.ItemSize()); esize > 0 && int64(n) > math.MaxInt64/esize {
+		return 0, fmt.Errorf("npy: data size of shape %v overflows: %w", h.Descr.Shape, ErrShapeMismatch)
+	}
+	return int64(n) * int64(dt.ItemSize()), nil
+}
+
 func (h Header) String() string {
 	return fmt.Sprintf("Header{Major:%v, Minor:%v, Descr:{Type:%v, Fortran:%v, Shape:%v}}",
 		int(h.Major),
@@ -127,11 +272,13 @@ var (
 	int16Type      = reflect.TypeOf((*int16)(nil)).Elem()
 	int32Type      = reflect.TypeOf((*int32)(nil)).Elem()
 	int64Type      = reflect.TypeOf((*int64)(nil)).Elem()
+	float16Type    = reflect.TypeOf((*float16.Num)(nil)).Elem()
 	float32Type    = reflect.TypeOf((*float32)(nil)).Elem()
 	float64Type    = reflect.TypeOf((*float64)(nil)).Elem()
 	complex64Type  = reflect.TypeOf((*complex64)(nil)).Elem()
 	complex128Type = reflect.TypeOf((*complex128)(nil)).Elem()
 	stringType     = reflect.TypeOf((*string)(nil)).Elem()
+	voidType       = reflect.TypeOf((*[]byte)(nil)).Elem()
 	anyType        = reflect.TypeOf((*interface{})(nil)).Elem()
 
 	trueUint8  = []byte{1}
@@ -229,7 +376,17 @@ func newDtype(str string) (dType, error) {
 		}
 	}
 	if dt.rt == nil {
-		return dt, fmt.Errorf("npy: no reflect.Type for dtype=%v", str)
+		// fall back to opaque, fixed-size, elements for the void,
+		// structured and sub-array data types, read as raw bytes.
+		descr, err := ParseDescr(str)
+		if err != nil {
+			return dt, err
+		}
+		if (descr.kind != 'V' && descr.names == nil && descr.subarr == nil) || descr.esize < 0 {
+			return dt, fmt.Errorf("npy: no reflect.Type for dtype=%v", str)
+		}
+		dt.rt = voidType
+		dt.size = descr.esize
 	}
 
 	switch dt.str[0] {
@@ -243,6 +400,18 @@ func newDtype(str string) (dType, error) {
 	return dt, nil
 }
 
+// stringDescr returns the array description of this byte-string ('S')
+// or unicode ('U') data type.
+func (dt dType) stringDescr() *ArrayDescr {
+	descr := &ArrayDescr{kind: 'S', order: dt.order, esize: dt.size, align: 1}
+	if dt.utf {
+		descr.kind = 'U'
+		descr.esize *= 4 // UCS-4 code points.
+		descr.align = 4
+	}
+	return descr
+}
+
 var nativeEndian struct {
 	binary.ByteOrder
 }
@@ -0,0 +1,378 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parChunk is the size, in bytes, of the chunks of array data read or
+// written concurrently by ReadAt and WriteAt.
+const parChunk = 1 << 20
+
+// WithReadWorkers sets the number of goroutines ReadAt uses to read and
+// decode the array data.
+// The default (zero) is runtime.GOMAXPROCS(0).
+func WithReadWorkers(n int) ReadOption {
+	return func(cfg *readConfig) {
+		cfg.workers = n
+	}
+}
+
+// WithWriteWorkers sets the number of goroutines WriteAt uses to encode
+// and write the array data.
+// The default (zero) is runtime.GOMAXPROCS(0).
+func WithWriteWorkers(n int) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.workers = n
+	}
+}
+
+// ReadAt reads the data from the r NumPy data file io.ReaderAt, into the
+// provided pointed at value ptr, like Read.
+//
+// When ptr is a *Array or a pointer to a slice of numeric values (bool,
+// (u)int{8,16,32,64}, float{32,64} and complex{64,128}), the array data
+// is split into chunks that are read and decoded concurrently.
+// Other values are read sequentially, like Read does.
+// The result does not depend on the number of goroutines.
+func ReadAt(r io.ReaderAt, ptr interface{}, opts ...ReadOption) error {
+	size := int64(math.MaxInt64)
+	if sr, ok := r.(interface{ Size() int64 }); ok {
+		// bytes.Reader, strings.Reader, io.SectionReader, ...
+		size = sr.Size()
+	}
+	rr, err := NewReader(io.NewSectionReader(r, 0, size), opts...)
+	if err != nil {
+		return err
+	}
+	return rr.readError(ptr, readAt(rr, r, ptr), 0, nil)
+}
+
+// readAt reads the array data described by the header of rr from r, into
+// the provided pointed at value ptr.
+func readAt(rr *Reader, r io.ReaderAt, ptr interface{}) error {
+	dt, err := rr.goDtype()
+	if err != nil {
+		return rr.Read(ptr)
+	}
+	descr, err := rr.arrayDescr(dt)
+	if err != nil || !descr.isNumeric() {
+		return rr.Read(ptr)
+	}
+	rt, err := descr.goType()
+	if err != nil {
+		return rr.Read(ptr)
+	}
+
+	var (
+		hdr    = rr.Header
+		nelems = numElems(hdr.Descr.Shape)
+		data   reflect.Value
+	)
+	switch ptr.(type) {
+	case *Array:
+		err = rr.checkSize(nelems, descr.esize)
+		if err != nil {
+			return err
+		}
+		data = reflect.MakeSlice(reflect.SliceOf(rt), nelems, nelems)
+	default:
+		rv := reflect.ValueOf(ptr)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() ||
+			rv.Elem().Kind() != reflect.Slice || rv.Elem().Type().Elem() != rt {
+			return rr.Read(ptr)
+		}
+		if rr.cfg.layout == CLayout && hdr.Descr.Fortran && len(hdr.Descr.Shape) > 1 {
+			// elements need to be reordered.
+			return rr.Read(ptr)
+		}
+		err = rr.checkSize(nelems, descr.esize)
+		if err != nil {
+			return err
+		}
+		// like Read, fill the provided slice if it is not empty.
+		data = rv.Elem()
+		switch {
+		case data.Len() == 0:
+			data = reflect.MakeSlice(data.Type(), nelems, nelems)
+			rv.Elem().Set(data)
+		case data.Len() > nelems:
+			data = data.Slice(0, nelems)
+		}
+	}
+
+	esize := descr.esize
+	err = parallelize(rr.cfg.workers, data.Len(), esize, func(beg, end int, buf []byte) error {
+		raw := buf[:(end-beg)*esize]
+		n, err := r.ReadAt(raw, hdr.DataOffset+int64(beg*esize))
+		if n < len(raw) {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return rr.payloadError(int64(beg*esize+n), err)
+		}
+		descr.decode(data.Slice(beg, end).Interface(), raw)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if arr, ok := ptr.(*Array); ok {
+		*arr = Array{
+			descr:   *descr,
+			shape:   hdr.Descr.Shape,
+			fortran: hdr.Descr.Fortran,
+			data:    data.Interface(),
+		}
+		err = arr.setupStrides()
+		if err != nil {
+			return fmt.Errorf("could not setup array strides for %q: %w", hdr.Descr.Type, err)
+		}
+	}
+	return nil
+}
+
+// ReadRows reads the rows [beg, end) of the array data from the r NumPy
+// data file io.ReaderAt, into the provided pointed at value ptr, like Read.
+// Rows are the sub-arrays along the first axis: the value read has the
+// shape of the on-disk array, with its first dimension set to end-beg.
+//
+// Only the header and the requested rows are read from r.
+// ReadRows returns an error for 0-dimensional arrays, for multi-dimensional
+// arrays laid out in Fortran-order (whose rows are not contiguous) and for
+// object arrays.
+func ReadRows(r io.ReaderAt, ptr interface{}, beg, end int, opts ...ReadOption) error {
+	size := int64(math.MaxInt64)
+	if sr, ok := r.(interface{ Size() int64 }); ok {
+		size = sr.Size()
+	}
+	rr, err := NewReader(io.NewSectionReader(r, 0, size), opts...)
+	if err != nil {
+		return err
+	}
+	return rr.readError(ptr, readRows(rr, r, ptr, beg, end), 0, nil)
+}
+
+// readRows reads the rows [beg, end) of the array data described by the
+// header of rr from r, into the provided pointed at value ptr.
+func readRows(rr *Reader, r io.ReaderAt, ptr interface{}, beg, end int) error {
+	shape := rr.Header.Descr.Shape
+	switch {
+	case len(shape) == 0:
+		return fmt.Errorf("npy: can not read rows of a 0-dimensional array: %w", ErrShapeMismatch)
+	case beg < 0 || end < beg || end > shape[0]:
+		return fmt.Errorf("npy: invalid row range [%d, %d) for shape %v: %w", beg, end, shape, ErrShapeMismatch)
+	case rr.Header.Descr.Fortran && len(shape) > 1:
+		return fmt.Errorf("npy: can not read rows of a Fortran-ordered array: %w", ErrInvalidType)
+	}
+	descr, err := rr.dtype()
+	if err != nil {
+		return err
+	}
+	if descr.kind == 'O' || descr.esize < 0 {
+		return fmt.Errorf("npy: can not read rows of %q arrays: %w", rr.Header.Descr.Type, ErrInvalidType)
+	}
+
+	// the whole array data size does not overflow, so neither do the
+	// offsets of its rows.
+	_, err = rr.Header.DataSize()
+	if err != nil {
+		return err
+	}
+	rowElems, err := shapeSize(shape[1:])
+	if err != nil {
+		return err
+	}
+	if esize := int64(descr.esize); esize > 0 && int64(rowElems) > math.MaxInt64/esize {
+		return fmt.Errorf("npy: row size of shape %v overflows: %w", shape, ErrShapeMismatch)
+	}
+
+	var (
+		rowSize = int64(rowElems) * int64(descr.esize)
+		off     = rr.Header.DataOffset + int64(beg)*rowSize
+		n       = int64(end-beg) * rowSize
+	)
+	if avail := rr.avail; avail >= 0 {
+		avail -= int64(beg) * rowSize
+		rr.avail = max(avail, 0)
+	}
+	rr.r = io.NewSectionReader(r, off, n)
+	rr.Header.DataOffset = off
+	rr.Header.Descr.Shape = append([]int{end - beg}, shape[1:]...)
+
+	return rr.Read(ptr)
+}
+
+// WriteAt writes 'val' into 'w' in the NumPy data format, like Write.
+//
+// When val is an Array, a *Tensor[T] or a slice of numeric values (bool,
+// (u)int{8,16,32,64}, float{32,64} and complex{64,128}), the array data
+// is split into chunks that are encoded and written concurrently.
+// Other values are written sequentially, like Write does.
+// The output does not depend on the number of goroutines.
+func WriteAt(w io.WriterAt, val interface{}, opts ...WriteOption) error {
+	cfg := newWriteConfig(opts)
+
+	var arr Array
+	switch v := val.(type) {
+	case *Array:
+		arr = *v
+	case Array:
+		arr = v
+	case interface{ Array() (*Array, error) }:
+		a, err := v.Array()
+		if err != nil {
+			return err
+		}
+		arr = *a
+	default:
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Slice {
+			return Write(io.NewOffsetWriter(w, 0), val, opts...)
+		}
+		typ, err := dtypeFrom(rv, rv.Type())
+		if err != nil {
+			return Write(io.NewOffsetWriter(w, 0), val, opts...)
+		}
+		descr, err := ParseDescr(typ)
+		if err != nil || !descr.isNumeric() {
+			return Write(io.NewOffsetWriter(w, 0), val, opts...)
+		}
+		if rt, err := descr.goType(); err != nil || rt != rv.Type().Elem() {
+			return Write(io.NewOffsetWriter(w, 0), val, opts...)
+		}
+		arr = Array{
+			descr: descr,
+			shape: []int{rv.Len()},
+			data:  val,
+		}
+		err = arr.setupStrides()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !arr.descr.isNumeric() || reflect.ValueOf(arr.data).Kind() != reflect.Slice {
+		return writeArray(io.NewOffsetWriter(w, 0), arr)
+	}
+
+	arr, fortran := arrayLayout(arr)
+	var (
+		hdr   = newHeader()
+		buf   = new(bytes.Buffer)
+		esize = arr.descr.esize
+		data  = reflect.ValueOf(arr.data)
+	)
+	hdr.Descr.Type = arr.descr.Descr()
+	hdr.Descr.Fortran = fortran
+	hdr.Descr.Shape = arr.shape
+
+	err := writeHeader(buf, hdr)
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteAt(buf.Bytes(), 0)
+	if err != nil {
+		return err
+	}
+
+	var (
+		offset = int64(buf.Len())
+		nelems = numElems(arr.shape)
+	)
+	if data.Len() < nelems {
+		return fmt.Errorf("npy: array data length %d does not match shape %v: %w", data.Len(), arr.shape, ErrShapeMismatch)
+	}
+	data = data.Slice(0, nelems)
+
+	return parallelize(cfg.workers, nelems, esize, func(beg, end int, buf []byte) error {
+		raw := buf[:(end-beg)*esize]
+		arr.descr.encode(raw, data.Slice(beg, end).Interface())
+		_, err := w.WriteAt(raw, offset+int64(beg*esize))
+		return err
+	})
+}
+
+// isNumeric returns whether the data type describes booleans or numbers.
+func (dt ArrayDescr) isNumeric() bool {
+	switch dt.kind {
+	case 'b', 'i', 'u', 'f', 'c':
+		return dt.subarr == nil && dt.names == nil && dt.esize > 0 && !dt.isExtended()
+	}
+	return false
+}
+
+// parallelize splits n elements of esize bytes into chunks, and calls f
+// concurrently on the [beg, end) range of elements of each chunk, using
+// the provided number of goroutines.
+// The buffer passed to f can hold a whole chunk, and is reused between
+// calls within the same goroutine.
+//
+// parallelize returns the error of the first failing chunk, if any.
+func parallelize(workers, n, esize int, f func(beg, end int, buf []byte) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	chunk := parChunk / esize
+	if chunk == 0 {
+		chunk = 1
+	}
+	nchunks := (n + chunk - 1) / chunk
+	if workers > nchunks {
+		workers = nchunks
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+		stop atomic.Bool
+
+		mu   sync.Mutex
+		ierr = nchunks // index of the first failing chunk.
+		err  error
+	)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, chunk*esize)
+			for !stop.Load() {
+				k := int(next.Add(1) - 1)
+				if k >= nchunks {
+					return
+				}
+				beg := k * chunk
+				end := beg + chunk
+				if end > n {
+					end = n
+				}
+				e := f(beg, end, buf)
+				if e == nil {
+					continue
+				}
+				mu.Lock()
+				if k < ierr {
+					ierr = k
+					err = e
+				}
+				mu.Unlock()
+				stop.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,455 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sbinet/npyio/npy/float16"
+	"gonum.org/v1/gonum/mat"
+)
+
+// memWriterAt is an in-memory io.WriterAt.
+type memWriterAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.buf) {
+		w.buf = append(w.buf, make([]byte, end-len(w.buf))...)
+	}
+	return copy(w.buf[off:], p), nil
+}
+
+func TestReadAt(t *testing.T) {
+	fnames, err := filepath.Glob("../testdata/*.npy")
+	if err != nil {
+		t.Fatalf("could not find files: %+v", err)
+	}
+	for _, fname := range fnames {
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			t.Fatalf("could not read file: %+v", err)
+		}
+		var want Array
+		err = Read(bytes.NewReader(raw), &want)
+		if err != nil {
+			continue
+		}
+		for _, workers := range []int{1, 3} {
+			t.Run(fmt.Sprintf("%s-%d", filepath.Base(fname), workers), func(t *testing.T) {
+				var got Array
+				err := ReadAt(bytes.NewReader(raw), &got, WithReadWorkers(workers))
+				if err != nil {
+					t.Fatalf("could not read array: %+v", err)
+				}
+				if !reflect.DeepEqual(got, want) && got.String() != want.String() {
+					t.Fatalf("invalid array:\ngot= %v\nwant=%v", got, want)
+				}
+			})
+		}
+	}
+
+	// large enough to span multiple chunks.
+	data := make([]complex128, 3*parChunk/16+5)
+	for i := range data {
+		data[i] = complex(float64(i), -float64(i))
+	}
+	buf := new(bytes.Buffer)
+	err = Write(buf, data)
+	if err != nil {
+		t.Fatalf("could not write data: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	for _, workers := range []int{0, 1, 2, 5} {
+		t.Run(fmt.Sprintf("chunks-%d", workers), func(t *testing.T) {
+			var got []complex128
+			err := ReadAt(bytes.NewReader(raw), &got, WithReadWorkers(workers))
+			if err != nil {
+				t.Fatalf("could not read data: %+v", err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Fatalf("invalid data")
+			}
+		})
+	}
+
+	t.Run("prealloc", func(t *testing.T) {
+		got := make([]complex128, 10)
+		err := ReadAt(bytes.NewReader(raw), &got)
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if !reflect.DeepEqual(got, data[:10]) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, data[:10])
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var got []complex128
+		err := ReadAt(bytes.NewReader(raw[:len(raw)-1]), &got, WithReadWorkers(3))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, io.ErrUnexpectedEOF)
+		}
+	})
+
+	t.Run("type-mismatch", func(t *testing.T) {
+		var got []float64
+		err := ReadAt(bytes.NewReader(raw), &got)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, ErrTypeMismatch)
+		}
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		f, err := os.Open("../testdata/data_float64_2x3_forder.npy")
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+
+		var got mat.Dense
+		err = ReadAt(f, &got)
+		if err != nil {
+			t.Fatalf("could not read matrix: %+v", err)
+		}
+		want := mat.NewDense(2, 3, []float64{0, 2, 4, 1, 3, 5})
+		if !mat.Equal(&got, want) {
+			t.Fatalf("invalid matrix:\ngot= %v\nwant=%v", mat.Formatted(&got), mat.Formatted(want))
+		}
+	})
+}
+
+// rangeReaderAt is an io.ReaderAt recording the range of array data read.
+type rangeReaderAt struct {
+	r   *bytes.Reader
+	mu  sync.Mutex
+	beg int64 // smallest offset read after the header.
+	end int64 // largest offset read.
+	hdr int64 // size of the header.
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if off >= r.hdr && (r.beg < 0 || off < r.beg) {
+		r.beg = off
+	}
+	r.end = max(r.end, off+int64(n))
+	return n, err
+}
+
+func (r *rangeReaderAt) Size() int64 { return r.r.Size() }
+
+func TestReadRows(t *testing.T) {
+	data := make([]int32, 5*3)
+	for i := range data {
+		data[i] = int32(i)
+	}
+	buf := new(bytes.Buffer)
+	err := WriteShaped(buf, data, []int{5, 3}, false)
+	if err != nil {
+		t.Fatalf("could not write data: %+v", err)
+	}
+	raw := buf.Bytes()
+	hdr, err := ReadHeader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("could not read header: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		beg, end int
+	}{
+		{0, 5},
+		{1, 3},
+		{4, 5},
+		{2, 2},
+	} {
+		t.Run(fmt.Sprintf("rows-%d-%d", tc.beg, tc.end), func(t *testing.T) {
+			r := &rangeReaderAt{r: bytes.NewReader(raw), beg: -1, hdr: hdr.DataOffset}
+			var got []int32
+			err := ReadRows(r, &got, tc.beg, tc.end)
+			if err != nil {
+				t.Fatalf("could not read rows: %+v", err)
+			}
+			if want := data[3*tc.beg : 3*tc.end]; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid rows:\ngot= %v\nwant=%v", got, want)
+			}
+			if tc.beg == tc.end {
+				return
+			}
+			if got, want := r.beg, hdr.DataOffset+int64(3*4*tc.beg); got != want {
+				t.Fatalf("invalid first offset read:\ngot= %d\nwant=%d", got, want)
+			}
+			if got, want := r.end, hdr.DataOffset+int64(3*4*tc.end); got != want {
+				t.Fatalf("invalid last offset read:\ngot= %d\nwant=%d", got, want)
+			}
+		})
+	}
+
+	t.Run("array", func(t *testing.T) {
+		var arr Array
+		err := ReadRows(bytes.NewReader(raw), &arr, 3, 5)
+		if err != nil {
+			t.Fatalf("could not read rows: %+v", err)
+		}
+		if got, want := arr.Shape(), []int{2, 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+		if got, want := arr.Data(), data[9:]; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+		}
+	})
+
+	t.Run("matrix", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := Write(buf, mat.NewDense(3, 2, []float64{1, 2, 3, 4, 5, 6}))
+		if err != nil {
+			t.Fatalf("could not write matrix: %+v", err)
+		}
+		var got mat.Dense
+		err = ReadRows(bytes.NewReader(buf.Bytes()), &got, 1, 3)
+		if err != nil {
+			t.Fatalf("could not read rows: %+v", err)
+		}
+		want := mat.NewDense(2, 2, []float64{3, 4, 5, 6})
+		if !mat.Equal(&got, want) {
+			t.Fatalf("invalid matrix:\ngot= %v\nwant=%v", mat.Formatted(&got), mat.Formatted(want))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		scalar := new(bytes.Buffer)
+		err := Write(scalar, int32(42))
+		if err != nil {
+			t.Fatalf("could not write scalar: %+v", err)
+		}
+		overflow := new(bytes.Buffer)
+		ohdr := newHeader()
+		ohdr.Descr.Type = "<i4"
+		ohdr.Descr.Shape = []int{0, 1 << 32, 1 << 30}
+		err = writeHeader(overflow, ohdr)
+		if err != nil {
+			t.Fatalf("could not write header: %+v", err)
+		}
+		fortran := new(bytes.Buffer)
+		err = WriteShaped(fortran, data, []int{5, 3}, true)
+		if err != nil {
+			t.Fatalf("could not write fortran data: %+v", err)
+		}
+
+		for _, tc := range []struct {
+			name     string
+			raw      []byte
+			beg, end int
+			err      error
+		}{
+			{"negative", raw, -1, 2, ErrShapeMismatch},
+			{"reversed", raw, 3, 2, ErrShapeMismatch},
+			{"out-of-range", raw, 0, 6, ErrShapeMismatch},
+			{"scalar", scalar.Bytes(), 0, 1, ErrShapeMismatch},
+			{"fortran", fortran.Bytes(), 0, 1, ErrInvalidType},
+			{"truncated", raw[:len(raw)-1], 3, 5, io.ErrUnexpectedEOF},
+			{"row-overflow", overflow.Bytes(), 0, 0, ErrShapeMismatch},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				var got []int32
+				err := ReadRows(bytes.NewReader(tc.raw), &got, tc.beg, tc.end)
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.err)
+				}
+			})
+		}
+	})
+}
+
+func TestWriteAt(t *testing.T) {
+	f16 := make([]float16.Num, 3*parChunk/2+7)
+	for i := range f16 {
+		f16[i] = float16.New(float32(i % 2048))
+	}
+	f16s, err := NewArray(mustParseDescr(t, "<f2"), []int{len(f16)}, f16)
+	if err != nil {
+		t.Fatalf("could not create array: %+v", err)
+	}
+
+	bools := make([]bool, 2*parChunk+1)
+	for i := range bools {
+		bools[i] = i%3 == 0
+	}
+
+	i64 := make([]int64, 3*parChunk/8+1)
+	for i := range i64 {
+		i64[i] = int64(i) - 42
+	}
+
+	arr := loadArray(t, "../testdata/data_float32_2x3_forder.npy")
+	view, err := arr.Slice(1, 1, 3)
+	if err != nil {
+		t.Fatalf("could not slice array: %+v", err)
+	}
+
+	ten, err := NewTensor([]int{3, 4}, []uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+	if err != nil {
+		t.Fatalf("could not create tensor: %+v", err)
+	}
+	ten.Shape = []int{2, 2}
+	ten.Strides = []int{4, 2}
+
+	for _, tc := range []struct {
+		name string
+		val  any
+	}{
+		{"bools", bools},
+		{"int64s", i64},
+		{"float16s", f16s},
+		{"array", arr},
+		{"view", view},
+		{"tensor", ten},
+		{"empty", []float64{}},
+		{"scalar", 42.0},
+		{"strings", []string{"hello", "world"}},
+		{"dense", mat.NewDense(2, 3, []float64{1, 2, 3, 4, 5, 6})},
+	} {
+		want := new(bytes.Buffer)
+		err := Write(want, tc.val)
+		if err != nil {
+			t.Fatalf("could not write %s: %+v", tc.name, err)
+		}
+		for _, workers := range []int{0, 1, 3} {
+			t.Run(fmt.Sprintf("%s-%d", tc.name, workers), func(t *testing.T) {
+				got := new(memWriterAt)
+				err := WriteAt(got, tc.val, WithWriteWorkers(workers))
+				if err != nil {
+					t.Fatalf("could not write: %+v", err)
+				}
+				if !bytes.Equal(got.buf, want.Bytes()) {
+					t.Fatalf("invalid output")
+				}
+			})
+		}
+	}
+}
+
+func mustParseDescr(t testing.TB, descr string) ArrayDescr {
+	t.Helper()
+	dt, err := ParseDescr(descr)
+	if err != nil {
+		t.Fatalf("could not parse descr %q: %+v", descr, err)
+	}
+	return dt
+}
+
+func BenchmarkReadAt(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		data any
+	}{
+		{"float16", make([]float16.Num, 1<<23)},
+		{"complex128", make([]complex128, 1<<21)},
+	} {
+		var (
+			buf = new(bytes.Buffer)
+			arr Array
+		)
+		switch data := tc.data.(type) {
+		case []float16.Num:
+			a, err := NewArray(mustParseDescr(b, ">f2"), []int{len(data)}, data)
+			if err != nil {
+				b.Fatalf("could not create array: %+v", err)
+			}
+			arr = *a
+		case []complex128:
+			a, err := NewArray(mustParseDescr(b, ">c16"), []int{len(data)}, data)
+			if err != nil {
+				b.Fatalf("could not create array: %+v", err)
+			}
+			arr = *a
+		}
+		err := Write(buf, arr)
+		if err != nil {
+			b.Fatalf("could not write data: %+v", err)
+		}
+		raw := buf.Bytes()
+
+		b.Run(tc.name+"-read", func(b *testing.B) {
+			if err := Read(bytes.NewReader(raw), new(Array)); err != nil {
+				b.Skipf("sequential read not supported: %+v", err)
+			}
+			b.SetBytes(int64(len(raw)))
+			for i := 0; i < b.N; i++ {
+				var arr Array
+				err := Read(bytes.NewReader(raw), &arr)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s-workers=%d", tc.name, workers), func(b *testing.B) {
+				b.SetBytes(int64(len(raw)))
+				for i := 0; i < b.N; i++ {
+					var arr Array
+					err := ReadAt(bytes.NewReader(raw), &arr, WithReadWorkers(workers))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkWriteAt(b *testing.B) {
+	for _, tc := range []struct {
+		name  string
+		descr string
+		data  any
+	}{
+		{"float16", ">f2", make([]float16.Num, 1<<23)},
+		{"complex128", ">c16", make([]complex128, 1<<21)},
+	} {
+		arr, err := NewArray(mustParseDescr(b, tc.descr), []int{reflect.ValueOf(tc.data).Len()}, tc.data)
+		if err != nil {
+			b.Fatalf("could not create array: %+v", err)
+		}
+		size := int64(reflect.ValueOf(tc.data).Len() * arr.descr.esize)
+
+		b.Run(tc.name+"-write", func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				err := Write(io.Discard, arr)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s-workers=%d", tc.name, workers), func(b *testing.B) {
+				w := &memWriterAt{buf: make([]byte, size+128)}
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					err := WriteAt(w, arr, WithWriteWorkers(workers))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
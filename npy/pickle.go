@@ -7,6 +7,7 @@ package npy
 //go:generate go run ./gen-pickle.go
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -25,16 +26,184 @@ func newUnpickler(r io.Reader) pickle.Unpickler {
 	return u
 }
 
+// unpickle decodes the provided pickled data.
+// Panics raised while decoding malformed data are returned as errors.
+func unpickle(raw []byte) (v any, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			v = nil
+			err = fmt.Errorf("npy: invalid pickle data: %v", e)
+		}
+	}()
+	err = checkPickle(raw)
+	if err != nil {
+		return nil, err
+	}
+	pkl := newUnpickler(bytes.NewReader(raw))
+	return pkl.Load()
+}
+
+// checkPickle scans the opcodes of the provided pickled data and checks
+// that the arguments of all opcodes fit within the data.
+//
+// The pickle decoder allocates buffers as large as the declared length of
+// length-prefixed arguments (strings, bytes, frames, ...).
+// checkPickle prevents malicious data from exhausting memory that way.
+func checkPickle(raw []byte) error {
+	return scanPickle(&pickleBytes{raw: raw})
+}
+
+// pickleSource provides the pickled data scanned by scanPickle.
+type pickleSource interface {
+	// next consumes the next n bytes.
+	next(n int) ([]byte, error)
+	// line consumes the next bytes, up to and including a newline.
+	line() error
+	// frame checks a frame of n bytes.
+	frame(n int) error
+}
+
+// scanPickle scans the opcodes of the pickled data, up to and including
+// the STOP opcode, and checks that the arguments of all opcodes fit within
+// the data.
+func scanPickle(src pickleSource) error {
+	size := func(n int) (int, error) {
+		p, err := src.next(n)
+		if err != nil {
+			return 0, err
+		}
+		v := 0
+		for i := n - 1; i >= 0; i-- {
+			v = v<<8 | int(p[i])
+		}
+		if v < 0 || n == 8 && p[n-1]&0x80 != 0 {
+			return 0, fmt.Errorf("npy: invalid pickle argument size")
+		}
+		return v, nil
+	}
+
+	for {
+		p, err := src.next(1)
+		if err != nil {
+			return err
+		}
+		op := p[0]
+		switch op {
+		case '.': // STOP
+			return nil
+
+		case '(', '0', '1', '2', 'N', 'R', 'a', 'b', 'd', '}', 'e', 'l', ']',
+			'o', 's', 'u', 't', ')', 'Q',
+			'\x81', '\x85', '\x86', '\x87', '\x88', '\x89', '\x8f',
+			'\x90', '\x91', '\x92', '\x93', '\x94', '\x97', '\x98':
+			// no argument.
+
+		case 'K', 'h', 'q', '\x80', '\x82':
+			_, err = src.next(1)
+		case 'M', '\x83':
+			_, err = src.next(2)
+		case 'J', 'j', 'r', '\x84':
+			_, err = src.next(4)
+		case 'G':
+			_, err = src.next(8)
+
+		case 'U', 'C', '\x8c', '\x8a':
+			var n int
+			n, err = size(1)
+			if err == nil {
+				_, err = src.next(n)
+			}
+		case 'T', 'B', 'X', '\x8b':
+			var n int
+			n, err = size(4)
+			if err == nil {
+				_, err = src.next(n)
+			}
+		case '\x8e', '\x8d', '\x96':
+			var n int
+			n, err = size(8)
+			if err == nil {
+				_, err = src.next(n)
+			}
+		case '\x95': // FRAME
+			var n int
+			n, err = size(8)
+			if err == nil {
+				err = src.frame(n)
+			}
+
+		case 'I', 'L', 'F', 'S', 'V', 'P', 'g', 'p':
+			err = src.line()
+		case 'c', 'i':
+			err = src.line()
+			if err == nil {
+				err = src.line()
+			}
+
+		default:
+			return fmt.Errorf("npy: invalid pickle opcode 0x%02x", op)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// pickleBytes is a pickleSource reading from a byte slice.
+type pickleBytes struct {
+	raw []byte
+	pos int
+}
+
+func (src *pickleBytes) next(n int) ([]byte, error) {
+	if n > len(src.raw)-src.pos {
+		return nil, io.ErrUnexpectedEOF
+	}
+	p := src.raw[src.pos : src.pos+n]
+	src.pos += n
+	return p, nil
+}
+
+func (src *pickleBytes) line() error {
+	i := bytes.IndexByte(src.raw[src.pos:], '\n')
+	if i < 0 {
+		return io.ErrUnexpectedEOF
+	}
+	src.pos += i + 1
+	return nil
+}
+
+func (src *pickleBytes) frame(n int) error {
+	if n > len(src.raw)-src.pos {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
 // ClassLoader provides a python class loader mechanism for python pickles
-// containing numpy.dtype and numpy.ndarray values.
+// containing numpy.dtype, numpy.ndarray and numpy scalar values.
+//
+// Both the numpy-1.x (numpy.core) and numpy-2.x (numpy._core) module
+// layouts are supported.
 func ClassLoader(module, name string) (any, error) {
 	switch module + "." + name {
 	case "numpy.dtype":
 		return &ArrayDescr{}, nil
 	case "numpy.ndarray":
 		return &Array{}, nil
-	case "numpy.core.multiarray._reconstruct":
+	case "numpy.core.multiarray._reconstruct",
+		"numpy._core.multiarray._reconstruct":
 		return reconstruct{}, nil
+	case "numpy.core.multiarray.scalar",
+		"numpy._core.multiarray.scalar":
+		return scalar{}, nil
+	case "builtins.bool", "builtins.bytes", "builtins.complex",
+		"builtins.float", "builtins.int", "builtins.object",
+		"builtins.str":
+		// python types may be referenced by dtype metadata.
+		// (e.g. h5py's string dtypes: {"vlen": str})
+		return py.NewGenericClass(module, name), nil
 	}
 
 	// FIXME(sbinet): use errors.ErrUnsupported when Go>=1.21.
@@ -79,6 +248,49 @@ func (reconstruct) Call(args ...any) (any, error) {
 	return subtype, nil
 }
 
+// scalar reconstructs numpy scalars (numpy.float64, numpy.str_, ...)
+// into their corresponding Go values.
+type scalar struct{}
+
+var _ py.Callable = (*scalar)(nil)
+
+func (scalar) Call(args ...any) (any, error) {
+	switch sz := len(args); sz {
+	case 1, 2:
+		// ok.
+	default:
+		return nil, fmt.Errorf("invalid tuple length (got=%d)", sz)
+	}
+
+	descr, ok := args[0].(*ArrayDescr)
+	if !ok {
+		return nil, fmt.Errorf("invalid scalar dtype type %T", args[0])
+	}
+
+	var data any
+	if len(args) == 2 {
+		data = args[1]
+	}
+
+	if descr.kind == 'O' {
+		// object scalars are pickled as the object itself.
+		return data, nil
+	}
+
+	var raw []byte
+	switch v := data.(type) {
+	case []byte:
+		raw = v
+	case string:
+		// python2 pickles store the scalar payload as a (latin-1) string.
+		raw = []byte(v)
+	default:
+		return nil, fmt.Errorf("invalid scalar payload type %T", data)
+	}
+
+	return descr.unmarshalScalar(raw)
+}
+
 func parseTuple(tup *py.Tuple, args ...any) error {
 	if want, got := tup.Len(), len(args); want != got {
 		return fmt.Errorf("invalid number of arguments: got=%d, want=%d", got, want)
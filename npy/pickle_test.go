@@ -5,12 +5,16 @@
 package npy
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/nlpodyssey/gopickle/pickle"
 	py "github.com/nlpodyssey/gopickle/types"
+	"github.com/sbinet/npyio/npy/float16"
 )
 
 func TestUnpickleDtype(t *testing.T) {
@@ -48,6 +52,384 @@ func TestUnpickleNdarray(t *testing.T) {
 	}
 }
 
+func TestUnpickleScalar(t *testing.T) {
+	for _, tc := range scalarTests {
+		t.Run(tc.name, func(t *testing.T) {
+			pkl := pickle.NewUnpickler(strings.NewReader(tc.pkl))
+			pkl.FindClass = ClassLoader
+
+			got, err := pkl.Load()
+			if err != nil {
+				t.Fatalf("could not unpickle: %+v", err)
+			}
+			if got, want := got, tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid unpickled data for %q:\ngot= %+v\nwant=%+v", tc.code, got, want)
+			}
+		})
+	}
+}
+
+func TestUnpickleDtypeMetadata(t *testing.T) {
+	for _, tc := range dtypeMetaTests {
+		t.Run(tc.name, func(t *testing.T) {
+			pkl := pickle.NewUnpickler(strings.NewReader(tc.pkl))
+			pkl.FindClass = ClassLoader
+
+			got, err := pkl.Load()
+			if err != nil {
+				t.Fatalf("could not unpickle: %+v", err)
+			}
+			if got, want := got, tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid unpickled data for %q:\ngot= %+v\nwant=%+v", tc.code, got, want)
+			}
+			if got, want := got.(*ArrayDescr).Metadata(), tc.want.meta; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid metadata for %q:\ngot= %+v\nwant=%+v", tc.code, got, want)
+			}
+		})
+	}
+}
+
+func TestPickleDtype(t *testing.T) {
+	var descrs []*ArrayDescr
+	for _, tc := range dtypeTests {
+		descrs = append(descrs, tc.want)
+	}
+	for _, tc := range dtypeMetaTests {
+		descrs = append(descrs, tc.want)
+	}
+
+	for i, want := range descrs {
+		t.Run(fmt.Sprintf("dtype-%d", i), func(t *testing.T) {
+			raw, err := pickleDumps(want)
+			if err != nil {
+				t.Fatalf("could not pickle dtype: %+v", err)
+			}
+
+			pkl := pickle.NewUnpickler(bytes.NewReader(raw))
+			pkl.FindClass = ClassLoader
+
+			got, err := pkl.Load()
+			if err != nil {
+				t.Fatalf("could not unpickle: %+v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid r/w round-trip:\ngot= %+v\nwant=%+v", got, want)
+			}
+		})
+	}
+}
+
+var dtypeMetaTests = []struct {
+	name string
+	code string
+	pkl  string
+	want *ArrayDescr
+}{
+	{
+		// pickle.dumps(h5py.string_dtype(), protocol=4)
+		name: "dtype-meta-h5py",
+		code: `np.dtype("O", metadata={"vlen": str})`,
+		pkl:  "\x80\x04\x95T\x00\x00\x00\x00\x00\x00\x00\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02O8\x94\x89\x88\x87\x94R\x94(K\x04\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK?}\x94\x8c\x04vlen\x94\x8c\bbuiltins\x94\x8c\x03str\x94\x93\x94st\x94b.",
+		want: &ArrayDescr{
+			kind:  'O',
+			order: nil,
+			esize: 8,
+			align: 8,
+			flags: 63,
+			meta: map[string]any{
+				"vlen": py.NewGenericClass("builtins", "str"),
+			},
+		},
+	},
+	{
+		// pickle.dumps(np.dtype("<f8", metadata={"unit": "m", "scale": 2.5, "tags": ["a", 1]}), protocol=4)
+		name: "dtype-meta-f8",
+		code: `np.dtype("<f8", metadata={"unit": "m", "scale": 2.5, "tags": ["a", 1]})`,
+		pkl:  "\x80\x04\x95h\x00\x00\x00\x00\x00\x00\x00\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f8\x94\x89\x88\x87\x94R\x94(K\x04\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00}\x94(\x8c\x04unit\x94\x8c\x01m\x94\x8c\x05scale\x94G@\x04\x00\x00\x00\x00\x00\x00\x8c\x04tags\x94]\x94(\x8c\x01a\x94K\x01eut\x94b.",
+		want: &ArrayDescr{
+			kind:  'f',
+			order: binary.LittleEndian,
+			esize: 8,
+			align: 8,
+			meta: map[string]any{
+				"unit":  "m",
+				"scale": 2.5,
+				"tags":  []any{"a", 1},
+			},
+		},
+	},
+}
+
+// scalarTests holds pickles of numpy scalars, as produced by numpy-1.x
+// (numpy.core.multiarray) and numpy-2.x (numpy._core.multiarray).
+var scalarTests = []struct {
+	name string
+	code string
+	pkl  string
+	want any
+}{
+	{
+		// pickle.dumps(np.bool_(True), protocol=4)
+		name: "numpy-1.x-0",
+		code: `np.bool_(True)`,
+		pkl:  "\x80\x04\x95b\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02b1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\x01\x94\x86\x94R\x94.",
+		want: true,
+	},
+	{
+		// pickle.dumps(np.int8(-2), protocol=4)
+		name: "numpy-1.x-1",
+		code: `np.int8(-2)`,
+		pkl:  "\x80\x04\x95b\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\xfe\x94\x86\x94R\x94.",
+		want: int8(-2),
+	},
+	{
+		// pickle.dumps(np.int16(-2), protocol=4)
+		name: "numpy-1.x-2",
+		code: `np.int16(-2)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\xfe\xff\x94\x86\x94R\x94.",
+		want: int16(-2),
+	},
+	{
+		// pickle.dumps(np.int32(-2), protocol=4)
+		name: "numpy-1.x-3",
+		code: `np.int32(-2)`,
+		pkl:  "\x80\x04\x95e\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\xfe\xff\xff\xff\x94\x86\x94R\x94.",
+		want: int32(-2),
+	},
+	{
+		// pickle.dumps(np.int64(-2), protocol=4)
+		name: "numpy-1.x-4",
+		code: `np.int64(-2)`,
+		pkl:  "\x80\x04\x95i\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\xfe\xff\xff\xff\xff\xff\xff\xff\x94\x86\x94R\x94.",
+		want: int64(-2),
+	},
+	{
+		// pickle.dumps(np.uint8(2), protocol=4)
+		name: "numpy-1.x-5",
+		code: `np.uint8(2)`,
+		pkl:  "\x80\x04\x95b\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\x02\x94\x86\x94R\x94.",
+		want: uint8(2),
+	},
+	{
+		// pickle.dumps(np.uint16(2), protocol=4)
+		name: "numpy-1.x-6",
+		code: `np.uint16(2)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\x02\x00\x94\x86\x94R\x94.",
+		want: uint16(2),
+	},
+	{
+		// pickle.dumps(np.uint32(2), protocol=4)
+		name: "numpy-1.x-7",
+		code: `np.uint32(2)`,
+		pkl:  "\x80\x04\x95e\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\x02\x00\x00\x00\x94\x86\x94R\x94.",
+		want: uint32(2),
+	},
+	{
+		// pickle.dumps(np.uint64(2), protocol=4)
+		name: "numpy-1.x-8",
+		code: `np.uint64(2)`,
+		pkl:  "\x80\x04\x95i\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x02\x00\x00\x00\x00\x00\x00\x00\x94\x86\x94R\x94.",
+		want: uint64(2),
+	},
+	{
+		// pickle.dumps(np.float16(1.5), protocol=4)
+		name: "numpy-1.x-9",
+		code: `np.float16(1.5)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\x00>\x94\x86\x94R\x94.",
+		want: float16.New(1.5),
+	},
+	{
+		// pickle.dumps(np.float32(1.5), protocol=4)
+		name: "numpy-1.x-10",
+		code: `np.float32(1.5)`,
+		pkl:  "\x80\x04\x95e\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\x00\x00\xc0?\x94\x86\x94R\x94.",
+		want: float32(1.5),
+	},
+	{
+		// pickle.dumps(np.float64(1.5), protocol=4)
+		name: "numpy-1.x-11",
+		code: `np.float64(1.5)`,
+		pkl:  "\x80\x04\x95i\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x00\x00\x00\x00\xf8?\x94\x86\x94R\x94.",
+		want: float64(1.5),
+	},
+	{
+		// pickle.dumps(np.complex64(1-2j), protocol=4)
+		name: "numpy-1.x-12",
+		code: `np.complex64(1-2j)`,
+		pkl:  "\x80\x04\x95i\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02c8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x80?\x00\x00\x00\xc0\x94\x86\x94R\x94.",
+		want: complex64(complex(1, -2)),
+	},
+	{
+		// pickle.dumps(np.complex128(1-2j), protocol=4)
+		name: "numpy-1.x-13",
+		code: `np.complex128(1-2j)`,
+		pkl:  "\x80\x04\x95r\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x03c16\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x10\x00\x00\x00\x00\x00\x00\xf0?\x00\x00\x00\x00\x00\x00\x00\xc0\x94\x86\x94R\x94.",
+		want: complex128(complex(1, -2)),
+	},
+	{
+		// pickle.dumps(np.bytes_(b"hello"), protocol=4)
+		name: "numpy-1.x-14",
+		code: `np.bytes_(b"hello")`,
+		pkl:  "\x80\x04\x95`\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02S5\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNK\x05K\x01K\x00t\x94bC\x05hello\x94\x86\x94R\x94.",
+		want: "hello",
+	},
+	{
+		// pickle.dumps(np.str_("hello, 世界!"), protocol=4)
+		name: "numpy-1.x-15",
+		code: `np.str_("hello, 世界!")`,
+		pkl:  "\x80\x04\x95\x84\x00\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x03U10\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNK(K\x04K\bt\x94bC(h\x00\x00\x00e\x00\x00\x00l\x00\x00\x00l\x00\x00\x00o\x00\x00\x00,\x00\x00\x00 \x00\x00\x00\x16N\x00\x00Lu\x00\x00!\x00\x00\x00\x94\x86\x94R\x94.",
+		want: "hello, 世界!",
+	},
+	{
+		// pickle.dumps(np.array([np.float64(1.5), np.int64(-2), "x"], dtype=object), protocol=4)
+		name: "numpy-1.x-16",
+		code: `np.array([np.float64(1.5), np.int64(-2), "x"], dtype=object)`,
+		pkl:  "\x80\x04\x95\x04\x01\x00\x00\x00\x00\x00\x00\x8c\x15numpy.core.multiarray\x94\x8c\f_reconstruct\x94\x93\x94\x8c\x05numpy\x94\x8c\andarray\x94\x93\x94K\x00\x85\x94C\x01b\x94\x87\x94R\x94(K\x01K\x03\x85\x94h\x03\x8c\x05dtype\x94\x93\x94\x8c\x02O8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK?t\x94b\x89]\x94(h\x00\x8c\x06scalar\x94\x93\x94h\f\x8c\x02f8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x00\x00\x00\x00\xf8?\x94\x86\x94R\x94h\x14h\f\x8c\x02i8\x94\x89\x88\x87\x94R\x94(K\x03h\x18NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\xfe\xff\xff\xff\xff\xff\xff\xff\x94\x86\x94R\x94\x8c\x01x\x94et\x94b.",
+		want: &Array{
+			descr: ArrayDescr{
+				kind:  'O',
+				order: nil,
+				esize: 8,
+				align: 8,
+				flags: 63,
+			},
+			shape:   []int{3},
+			strides: []int{8},
+			data:    pylist(float64(1.5), int64(-2), "x"),
+		},
+	},
+	{
+		// pickle.dumps(np.bool_(True), protocol=4)
+		name: "numpy-2.x-0",
+		code: `np.bool_(True)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02b1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\x01\x94\x86\x94R\x94.",
+		want: true,
+	},
+	{
+		// pickle.dumps(np.int8(-2), protocol=4)
+		name: "numpy-2.x-1",
+		code: `np.int8(-2)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\xfe\x94\x86\x94R\x94.",
+		want: int8(-2),
+	},
+	{
+		// pickle.dumps(np.int16(-2), protocol=4)
+		name: "numpy-2.x-2",
+		code: `np.int16(-2)`,
+		pkl:  "\x80\x04\x95d\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\xfe\xff\x94\x86\x94R\x94.",
+		want: int16(-2),
+	},
+	{
+		// pickle.dumps(np.int32(-2), protocol=4)
+		name: "numpy-2.x-3",
+		code: `np.int32(-2)`,
+		pkl:  "\x80\x04\x95f\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\xfe\xff\xff\xff\x94\x86\x94R\x94.",
+		want: int32(-2),
+	},
+	{
+		// pickle.dumps(np.int64(-2), protocol=4)
+		name: "numpy-2.x-4",
+		code: `np.int64(-2)`,
+		pkl:  "\x80\x04\x95j\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02i8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\xfe\xff\xff\xff\xff\xff\xff\xff\x94\x86\x94R\x94.",
+		want: int64(-2),
+	},
+	{
+		// pickle.dumps(np.uint8(2), protocol=4)
+		name: "numpy-2.x-5",
+		code: `np.uint8(2)`,
+		pkl:  "\x80\x04\x95c\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u1\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x01\x02\x94\x86\x94R\x94.",
+		want: uint8(2),
+	},
+	{
+		// pickle.dumps(np.uint16(2), protocol=4)
+		name: "numpy-2.x-6",
+		code: `np.uint16(2)`,
+		pkl:  "\x80\x04\x95d\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\x02\x00\x94\x86\x94R\x94.",
+		want: uint16(2),
+	},
+	{
+		// pickle.dumps(np.uint32(2), protocol=4)
+		name: "numpy-2.x-7",
+		code: `np.uint32(2)`,
+		pkl:  "\x80\x04\x95f\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\x02\x00\x00\x00\x94\x86\x94R\x94.",
+		want: uint32(2),
+	},
+	{
+		// pickle.dumps(np.uint64(2), protocol=4)
+		name: "numpy-2.x-8",
+		code: `np.uint64(2)`,
+		pkl:  "\x80\x04\x95j\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02u8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x02\x00\x00\x00\x00\x00\x00\x00\x94\x86\x94R\x94.",
+		want: uint64(2),
+	},
+	{
+		// pickle.dumps(np.float16(1.5), protocol=4)
+		name: "numpy-2.x-9",
+		code: `np.float16(1.5)`,
+		pkl:  "\x80\x04\x95d\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f2\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x02\x00>\x94\x86\x94R\x94.",
+		want: float16.New(1.5),
+	},
+	{
+		// pickle.dumps(np.float32(1.5), protocol=4)
+		name: "numpy-2.x-10",
+		code: `np.float32(1.5)`,
+		pkl:  "\x80\x04\x95f\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f4\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x04\x00\x00\xc0?\x94\x86\x94R\x94.",
+		want: float32(1.5),
+	},
+	{
+		// pickle.dumps(np.float64(1.5), protocol=4)
+		name: "numpy-2.x-11",
+		code: `np.float64(1.5)`,
+		pkl:  "\x80\x04\x95j\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02f8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x00\x00\x00\x00\xf8?\x94\x86\x94R\x94.",
+		want: float64(1.5),
+	},
+	{
+		// pickle.dumps(np.complex64(1-2j), protocol=4)
+		name: "numpy-2.x-12",
+		code: `np.complex64(1-2j)`,
+		pkl:  "\x80\x04\x95j\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02c8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x80?\x00\x00\x00\xc0\x94\x86\x94R\x94.",
+		want: complex64(complex(1, -2)),
+	},
+	{
+		// pickle.dumps(np.complex128(1-2j), protocol=4)
+		name: "numpy-2.x-13",
+		code: `np.complex128(1-2j)`,
+		pkl:  "\x80\x04\x95s\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x03c16\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\x10\x00\x00\x00\x00\x00\x00\xf0?\x00\x00\x00\x00\x00\x00\x00\xc0\x94\x86\x94R\x94.",
+		want: complex128(complex(1, -2)),
+	},
+	{
+		// pickle.dumps(np.bytes_(b"hello"), protocol=4)
+		name: "numpy-2.x-14",
+		code: `np.bytes_(b"hello")`,
+		pkl:  "\x80\x04\x95a\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x02S5\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNK\x05K\x01K\x00t\x94bC\x05hello\x94\x86\x94R\x94.",
+		want: "hello",
+	},
+	{
+		// pickle.dumps(np.str_("hello, 世界!"), protocol=4)
+		name: "numpy-2.x-15",
+		code: `np.str_("hello, 世界!")`,
+		pkl:  "\x80\x04\x95\x85\x00\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\x06scalar\x94\x93\x94\x8c\x05numpy\x94\x8c\x05dtype\x94\x93\x94\x8c\x03U10\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNK(K\x04K\bt\x94bC(h\x00\x00\x00e\x00\x00\x00l\x00\x00\x00l\x00\x00\x00o\x00\x00\x00,\x00\x00\x00 \x00\x00\x00\x16N\x00\x00Lu\x00\x00!\x00\x00\x00\x94\x86\x94R\x94.",
+		want: "hello, 世界!",
+	},
+	{
+		// pickle.dumps(np.array([np.float64(1.5), np.int64(-2), "x"], dtype=object), protocol=4)
+		name: "numpy-2.x-16",
+		code: `np.array([np.float64(1.5), np.int64(-2), "x"], dtype=object)`,
+		pkl:  "\x80\x04\x95\x05\x01\x00\x00\x00\x00\x00\x00\x8c\x16numpy._core.multiarray\x94\x8c\f_reconstruct\x94\x93\x94\x8c\x05numpy\x94\x8c\andarray\x94\x93\x94K\x00\x85\x94C\x01b\x94\x87\x94R\x94(K\x01K\x03\x85\x94h\x03\x8c\x05dtype\x94\x93\x94\x8c\x02O8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01|\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK?t\x94b\x89]\x94(h\x00\x8c\x06scalar\x94\x93\x94h\f\x8c\x02f8\x94\x89\x88\x87\x94R\x94(K\x03\x8c\x01<\x94NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\x00\x00\x00\x00\x00\x00\xf8?\x94\x86\x94R\x94h\x14h\f\x8c\x02i8\x94\x89\x88\x87\x94R\x94(K\x03h\x18NNNJ\xff\xff\xff\xffJ\xff\xff\xff\xffK\x00t\x94bC\b\xfe\xff\xff\xff\xff\xff\xff\xff\x94\x86\x94R\x94\x8c\x01x\x94et\x94b.",
+		want: &Array{
+			descr: ArrayDescr{
+				kind:  'O',
+				order: nil,
+				esize: 8,
+				align: 8,
+				flags: 63,
+			},
+			shape:   []int{3},
+			strides: []int{8},
+			data:    pylist(float64(1.5), int64(-2), "x"),
+		},
+	},
+}
+
 func pylist(sli ...any) *py.List {
 	return py.NewListFromSlice(sli)
 }
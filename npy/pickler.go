@@ -0,0 +1,437 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	py "github.com/nlpodyssey/gopickle/types"
+)
+
+// pickle opcodes.
+// See https://github.com/python/cpython/blob/main/Lib/pickletools.py
+const (
+	opMark           = '('
+	opStop           = '.'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opReduce         = 'R'
+	opBinUnicode     = 'X'
+	opAppends        = 'e'
+	opBuild          = 'b'
+	opGlobal         = 'c'
+	opEmptyDict      = '}'
+	opEmptyList      = ']'
+	opTuple          = 't'
+	opEmptyTuple     = ')'
+	opSetItems       = 'u'
+	opBinFloat       = 'G'
+	opProto          = '\x80'
+	opTuple1         = '\x85'
+	opTuple2         = '\x86'
+	opTuple3         = '\x87'
+	opNewTrue        = '\x88'
+	opNewFalse       = '\x89'
+	opLong1          = '\x8a'
+	opLong4          = '\x8b'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	pickleProtoVers3 = 3
+)
+
+// pickler serializes Go values into python pickles.
+//
+// pickler emits protocol 3 pickles, as numpy.save does for object arrays.
+// Only the values read back from object arrays (None, bool, int, float,
+// str, bytes, list, tuple, dict and numpy dtypes and arrays) can be
+// pickled.
+type pickler struct {
+	buf bytes.Buffer
+}
+
+// pickleDumps returns the python pickle representation of v.
+func pickleDumps(v any) ([]byte, error) {
+	var p pickler
+	p.buf.WriteByte(opProto)
+	p.buf.WriteByte(pickleProtoVers3)
+	err := p.dump(v)
+	if err != nil {
+		return nil, err
+	}
+	p.buf.WriteByte(opStop)
+	return p.buf.Bytes(), nil
+}
+
+func (p *pickler) dump(v any) error {
+	switch v := v.(type) {
+	case nil:
+		p.buf.WriteByte(opNone)
+		return nil
+
+	case bool:
+		switch v {
+		case true:
+			p.buf.WriteByte(opNewTrue)
+		default:
+			p.buf.WriteByte(opNewFalse)
+		}
+		return nil
+
+	case int:
+		p.int(int64(v))
+		return nil
+	case int64:
+		p.int(v)
+		return nil
+	case *big.Int:
+		p.long(v)
+		return nil
+
+	case float64:
+		p.float(v)
+		return nil
+
+	case string:
+		p.buf.WriteByte(opBinUnicode)
+		p.uint32(uint32(len(v)))
+		p.buf.WriteString(v)
+		return nil
+
+	case []byte:
+		p.bytes(v)
+		return nil
+
+	case *py.GenericClass:
+		p.global(v.Module, v.Name)
+		return nil
+
+	case *py.List:
+		return p.list(*v)
+
+	case *py.Tuple:
+		return p.tuple(*v...)
+
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := py.NewDict()
+		for _, k := range keys {
+			dict.Set(k, v[k])
+		}
+		return p.dump(dict)
+
+	case *py.Dict:
+		p.buf.WriteByte(opEmptyDict)
+		if v.Len() == 0 {
+			return nil
+		}
+		p.buf.WriteByte(opMark)
+		for _, e := range *v {
+			err := p.dump(e.Key)
+			if err != nil {
+				return err
+			}
+			err = p.dump(e.Value)
+			if err != nil {
+				return err
+			}
+		}
+		p.buf.WriteByte(opSetItems)
+		return nil
+
+	case ArrayDescr:
+		return p.descr(&v)
+
+	case *ArrayDescr:
+		return p.descr(v)
+
+	case Array:
+		return p.array(&v)
+
+	case *Array:
+		return p.array(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		vs := make([]any, rv.Len())
+		for i := range vs {
+			vs[i] = rv.Index(i).Interface()
+		}
+		return p.list(vs)
+	}
+
+	return fmt.Errorf("npy: can not pickle value of type %T: %w", v, ErrInvalidType)
+}
+
+func (p *pickler) int(v int64) {
+	switch {
+	case 0 <= v && v <= math.MaxUint8:
+		p.buf.WriteByte(opBinInt1)
+		p.buf.WriteByte(byte(v))
+	case 0 <= v && v <= math.MaxUint16:
+		p.buf.WriteByte(opBinInt2)
+		var buf [2]byte
+		binary.LittleEndian.PutUint16(buf[:], uint16(v))
+		p.buf.Write(buf[:])
+	case math.MinInt32 <= v && v <= math.MaxInt32:
+		p.buf.WriteByte(opBinInt)
+		p.uint32(uint32(int32(v)))
+	default:
+		p.long(big.NewInt(v))
+	}
+}
+
+// long writes v as a two's complement little-endian integer.
+func (p *pickler) long(v *big.Int) {
+	if v.IsInt64() && math.MinInt32 <= v.Int64() && v.Int64() <= math.MaxInt32 {
+		p.int(v.Int64())
+		return
+	}
+
+	// number of bytes needed, including the sign bit.
+	n := v.BitLen()/8 + 1
+	var raw []byte
+	switch v.Sign() {
+	case -1:
+		// two's complement: 2^(8n) + v
+		mod := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+		raw = mod.Add(mod, v).Bytes()
+	default:
+		raw = v.Bytes()
+	}
+	le := make([]byte, n)
+	for i, b := range raw {
+		le[len(raw)-1-i] = b
+	}
+	if v.Sign() < 0 {
+		for i := len(raw); i < n; i++ {
+			le[i] = 0xff
+		}
+	}
+
+	switch {
+	case n < 256:
+		p.buf.WriteByte(opLong1)
+		p.buf.WriteByte(byte(n))
+	default:
+		p.buf.WriteByte(opLong4)
+		p.uint32(uint32(n))
+	}
+	p.buf.Write(le)
+}
+
+func (p *pickler) float(v float64) {
+	p.buf.WriteByte(opBinFloat)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(v))
+	p.buf.Write(buf[:])
+}
+
+func (p *pickler) bytes(v []byte) {
+	switch {
+	case len(v) < 256:
+		p.buf.WriteByte(opShortBinBytes)
+		p.buf.WriteByte(byte(len(v)))
+	default:
+		p.buf.WriteByte(opBinBytes)
+		p.uint32(uint32(len(v)))
+	}
+	p.buf.Write(v)
+}
+
+func (p *pickler) uint32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	p.buf.Write(buf[:])
+}
+
+func (p *pickler) global(module, name string) {
+	p.buf.WriteByte(opGlobal)
+	p.buf.WriteString(module + "\n")
+	p.buf.WriteString(name + "\n")
+}
+
+func (p *pickler) list(vs []any) error {
+	p.buf.WriteByte(opEmptyList)
+	if len(vs) == 0 {
+		return nil
+	}
+	p.buf.WriteByte(opMark)
+	for _, v := range vs {
+		err := p.dump(v)
+		if err != nil {
+			return err
+		}
+	}
+	p.buf.WriteByte(opAppends)
+	return nil
+}
+
+func (p *pickler) tuple(vs ...any) error {
+	switch len(vs) {
+	case 0:
+		p.buf.WriteByte(opEmptyTuple)
+		return nil
+	case 1, 2, 3:
+		for _, v := range vs {
+			err := p.dump(v)
+			if err != nil {
+				return err
+			}
+		}
+		p.buf.WriteByte([]byte{opTuple1, opTuple2, opTuple3}[len(vs)-1])
+		return nil
+	default:
+		p.buf.WriteByte(opMark)
+		for _, v := range vs {
+			err := p.dump(v)
+			if err != nil {
+				return err
+			}
+		}
+		p.buf.WriteByte(opTuple)
+		return nil
+	}
+}
+
+// descr writes the numpy.dtype reduction of dt, following
+// arraydescr_reduce from numpy/_core/src/multiarray/descriptor.c
+func (p *pickler) descr(dt *ArrayDescr) error {
+	p.global("numpy", "dtype")
+	err := p.tuple(dt.typecode(), false, true)
+	if err != nil {
+		return fmt.Errorf("npy: could not pickle dtype: %w", err)
+	}
+	p.buf.WriteByte(opReduce)
+
+	var (
+		vers   = 3
+		esize  = -1
+		align  = -1
+		subarr any
+		names  any
+		fields any
+	)
+
+	if dt.subarr != nil {
+		shape := make([]any, len(dt.subarr.shape))
+		for i, v := range dt.subarr.shape {
+			shape[i] = v
+		}
+		subarr = py.NewTupleFromSlice([]any{
+			&dt.subarr.dtype,
+			py.NewTupleFromSlice(shape),
+		})
+	}
+
+	if dt.names != nil {
+		tup := make([]any, len(dt.names))
+		for i, name := range dt.names {
+			tup[i] = name
+		}
+		names = py.NewTupleFromSlice(tup)
+
+		dict := py.NewDict()
+		for _, name := range dt.names {
+			field := dt.fields[name]
+			dict.Set(name, py.NewTupleFromSlice([]any{field.dtype, int(field.offset)}))
+		}
+		fields = dict
+	}
+
+	switch {
+	case dt.names != nil, dt.subarr != nil:
+		esize = dt.esize
+		align = dt.align
+	default:
+		switch dt.kind {
+		case 'S', 'U', 'V':
+			esize = dt.esize
+			align = dt.align
+		}
+	}
+
+	state := []any{
+		vers, orderToString(dt.order),
+		subarr, names, fields,
+		esize, align, dt.flags,
+	}
+	if len(dt.meta) > 0 {
+		state[0] = 4
+		state = append(state, dt.meta)
+	}
+
+	err = p.tuple(state...)
+	if err != nil {
+		return fmt.Errorf("npy: could not pickle dtype state: %w", err)
+	}
+	p.buf.WriteByte(opBuild)
+	return nil
+}
+
+// array writes the numpy.ndarray reduction of arr, following
+// array_reduce from numpy/_core/src/multiarray/methods.c
+func (p *pickler) array(arr *Array) error {
+	if !arr.isCompact() || !arr.isCContiguous() {
+		arr = arr.Contiguous()
+	}
+
+	p.global("numpy.core.multiarray", "_reconstruct")
+	err := p.tuple(
+		py.NewGenericClass("numpy", "ndarray"),
+		py.NewTupleFromSlice([]any{0}),
+		[]byte("b"),
+	)
+	if err != nil {
+		return fmt.Errorf("npy: could not pickle ndarray: %w", err)
+	}
+	p.buf.WriteByte(opReduce)
+
+	shape := make([]any, len(arr.shape))
+	for i, v := range arr.shape {
+		shape[i] = v
+	}
+
+	var data any
+	switch arr.descr.kind {
+	case 'O':
+		rv := arr.values()
+		if !rv.IsValid() {
+			data = []any{arr.data}
+			break
+		}
+		elems := make([]any, rv.Len())
+		for i := range elems {
+			elems[i] = rv.Index(i).Interface()
+		}
+		data = elems
+	default:
+		raw, err := arr.descr.marshal(arr.data)
+		if err != nil {
+			return fmt.Errorf("npy: could not marshal ndarray data: %w", err)
+		}
+		data = raw
+	}
+
+	err = p.tuple(1, py.NewTupleFromSlice(shape), &arr.descr, false, data)
+	if err != nil {
+		return fmt.Errorf("npy: could not pickle ndarray state: %w", err)
+	}
+	p.buf.WriteByte(opBuild)
+	return nil
+}
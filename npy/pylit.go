@@ -0,0 +1,249 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	py "github.com/nlpodyssey/gopickle/types"
+)
+
+// pyLiteral parses python literals, as found in the header of a NumPy
+// data file (and in numpy data type descriptions.)
+//
+// Only the subset of python literals needed by numpy is supported:
+// strings, integers, True, False, None, tuples, lists and dicts.
+// Tuples, lists and dicts are returned as *py.Tuple, *py.List and *py.Dict.
+type pyLiteral struct {
+	buf string
+	pos int
+}
+
+// parsePyLiteral parses the python literal held by str.
+func parsePyLiteral(str string) (any, error) {
+	p := pyLiteral{buf: str}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.buf) {
+		return nil, p.errorf("unexpected trailing data %q", p.buf[p.pos:])
+	}
+	return v, nil
+}
+
+func (p *pyLiteral) errorf(format string, args ...any) error {
+	return fmt.Errorf("npy: invalid python literal at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *pyLiteral) skipSpaces() {
+	for p.pos < len(p.buf) {
+		switch p.buf[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *pyLiteral) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.buf) {
+		return 0
+	}
+	return p.buf[p.pos]
+}
+
+func (p *pyLiteral) value() (any, error) {
+	switch c := p.peek(); c {
+	case 0:
+		return nil, p.errorf("unexpected end of input")
+	case '\'', '"':
+		return p.str()
+	case '(':
+		vs, comma, err := p.seq('(', ')')
+		if err != nil {
+			return nil, err
+		}
+		if len(vs) == 1 && !comma {
+			// a parenthesized expression, not a 1-tuple.
+			return vs[0], nil
+		}
+		return py.NewTupleFromSlice(vs), nil
+	case '[':
+		vs, _, err := p.seq('[', ']')
+		if err != nil {
+			return nil, err
+		}
+		lst := py.NewList()
+		for _, v := range vs {
+			lst.Append(v)
+		}
+		return lst, nil
+	case '{':
+		return p.dict()
+	default:
+		switch {
+		case c == '-' || c == '+' || ('0' <= c && c <= '9'):
+			return p.int()
+		case strings.HasPrefix(p.buf[p.pos:], "True"):
+			p.pos += len("True")
+			return true, nil
+		case strings.HasPrefix(p.buf[p.pos:], "False"):
+			p.pos += len("False")
+			return false, nil
+		case strings.HasPrefix(p.buf[p.pos:], "None"):
+			p.pos += len("None")
+			return nil, nil
+		}
+		return nil, p.errorf("unexpected character %q", c)
+	}
+}
+
+func (p *pyLiteral) str() (string, error) {
+	quote := p.buf[p.pos]
+	p.pos++
+
+	var o strings.Builder
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		switch c {
+		case quote:
+			p.pos++
+			return o.String(), nil
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.buf) {
+				return "", p.errorf("unterminated string")
+			}
+			c = p.buf[p.pos]
+			p.pos++
+			switch c {
+			case '\\', '\'', '"':
+				o.WriteByte(c)
+			case 'n':
+				o.WriteByte('\n')
+			case 't':
+				o.WriteByte('\t')
+			case 'r':
+				o.WriteByte('\r')
+			case '0':
+				o.WriteByte(0)
+			case 'x', 'u', 'U':
+				n := 2
+				switch c {
+				case 'u':
+					n = 4
+				case 'U':
+					n = 8
+				}
+				if p.pos+n > len(p.buf) {
+					return "", p.errorf("invalid escape sequence")
+				}
+				v, err := strconv.ParseUint(p.buf[p.pos:p.pos+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(v)) {
+					return "", p.errorf("invalid escape sequence %q", p.buf[p.pos-2:p.pos+n])
+				}
+				o.WriteRune(rune(v))
+				p.pos += n
+			default:
+				o.WriteByte('\\')
+				o.WriteByte(c)
+			}
+		default:
+			o.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pyLiteral) int() (int, error) {
+	beg := p.pos
+	if c := p.buf[p.pos]; c == '-' || c == '+' {
+		p.pos++
+	}
+	for p.pos < len(p.buf) && '0' <= p.buf[p.pos] && p.buf[p.pos] <= '9' {
+		p.pos++
+	}
+	v, err := strconv.Atoi(p.buf[beg:p.pos])
+	if err != nil {
+		return 0, p.errorf("could not parse integer: %v", err)
+	}
+	if p.pos < len(p.buf) && p.buf[p.pos] == 'L' {
+		p.pos++ // python 2 long integers.
+	}
+	return v, nil
+}
+
+// seq parses a comma-separated sequence of values, enclosed in beg/end.
+// seq also reports whether the last value was followed by a comma.
+func (p *pyLiteral) seq(beg, end byte) ([]any, bool, error) {
+	p.pos++ // consume beg.
+	var (
+		vs    []any
+		comma bool
+	)
+	for {
+		if p.peek() == end {
+			p.pos++
+			return vs, comma, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, false, err
+		}
+		vs = append(vs, v)
+		comma = false
+		switch p.peek() {
+		case ',':
+			p.pos++
+			comma = true
+		case end:
+			p.pos++
+			return vs, comma, nil
+		default:
+			return nil, false, p.errorf("expected ',' or %q", end)
+		}
+	}
+}
+
+func (p *pyLiteral) dict() (*py.Dict, error) {
+	p.pos++ // consume '{'
+	dict := py.NewDict()
+	for {
+		if p.peek() == '}' {
+			p.pos++
+			return dict, nil
+		}
+		k, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ':' {
+			return nil, p.errorf("expected ':'")
+		}
+		p.pos++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(k, v)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return dict, nil
+		default:
+			return nil, p.errorf("expected ',' or '}'")
+		}
+	}
+}
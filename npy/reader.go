@@ -11,12 +11,12 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"reflect"
 	"regexp"
 	"strconv"
-	"strings"
-	"unicode/utf8"
 
+	py "github.com/nlpodyssey/gopickle/types"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -28,11 +28,52 @@ import (
 // If a *mat.Dense matrix is passed to Read, the numpy-array data is loaded
 // into the Dense matrix, honouring Fortran/C-order and dimensions/shape
 // parameters.
+// Complex numpy-arrays (<c8 or <c16) can be loaded into a *mat.CDense
+// matrix in the same way, and 1-dimensional numpy-arrays into a
+// *mat.VecDense vector.
+// 3-dimensional (batch, rows, cols) numpy-arrays can be loaded into a
+// *[]mat.Dense slice of matrices.
 //
-// Only numpy-arrays with up to 2 dimensions are supported.
-// Only numpy-arrays with elements convertible to float64 are supported.
-func Read(r io.Reader, ptr interface{}) error {
-	rr, err := NewReader(r)
+// If a *Tensor[T] is passed to Read, the numpy-array data is loaded into
+// the Tensor, together with its shape and memory layout.
+//
+// N-dimensional numpy-arrays can be loaded into nested slices and arrays
+// (e.g. *[][]float64 or *[2][3]int32.)
+// By default, the elements of Fortran-ordered numpy-arrays are laid out in
+// C-order (row-major) in nested slices and arrays, and as stored in flat
+// slices and arrays (e.g. *[]float64). Use WithLayout to change this.
+//
+// Extended precision longdouble ('<f16') and clongdouble ('<c32')
+// numpy-arrays can be loaded into a *big.Float or a *[]*big.Float, and into
+// a *BigComplex or a *[]BigComplex, respectively.
+// Their elements are expected in the x87 80-bit extended precision format,
+// padded to 16 bytes (as written by NumPy on x86-64 Linux.)
+// NaN elements, which can not be represented as a big.Float, are loaded as
+// nil *big.Float values.
+// The big.Float.Float64 method reports the loss of precision or overflow
+// when converting them to float64.
+// NumPy data files written on platforms with another longdouble format
+// (e.g. IEEE 754 binary128 on aarch64 or ppc64le Linux) use the same data
+// type strings: Read rejects their elements that are not valid in the x87
+// format, but can not detect all of them.
+//
+// Opaque ('V') numpy-arrays can be loaded into a *[][]byte or a *[][n]byte
+// slice, where n is the size of an element.
+// The elements of the other fixed-size data types without a Go equivalent
+// (e.g. structured data types) can also be loaded, as raw bytes, into a
+// *[][]byte slice.
+//
+// Any numpy-array, whatever its number of dimensions, can be loaded into
+// an *Array, together with its data type, shape and memory layout.
+//
+// gonum/mat values can only hold numpy-arrays with 1 (*mat.VecDense),
+// 2 (*mat.Dense, *mat.CDense) or 3 (*[]mat.Dense) dimensions, with
+// elements convertible to float64 (complex128 for *mat.CDense.)
+//
+// Read can be configured with ReadOptions, e.g. to limit the resources
+// consumed while decoding untrusted data.
+func Read(r io.Reader, ptr interface{}, opts ...ReadOption) error {
+	rr, err := NewReader(r, opts...)
 	if err != nil {
 		return err
 	}
@@ -40,22 +81,103 @@ func Read(r io.Reader, ptr interface{}) error {
 	return rr.Read(ptr)
 }
 
+// ReadHeader reads the header of the r NumPy data file io.Reader.
+// ReadHeader does not consume the array data: on success, r is positioned
+// at the start of the array data, i.e. at Header.DataOffset.
+func ReadHeader(r io.Reader, opts ...ReadOption) (Header, error) {
+	rr, err := NewReader(r, opts...)
+	if err != nil {
+		return Header{}, err
+	}
+	return rr.Header, nil
+}
+
+// DefaultMaxHeaderBytes is the default maximum size, in bytes, of the
+// header of a NumPy data file.
+// This is the same limit as the one NumPy itself enforces.
+const DefaultMaxHeaderBytes = 10000
+
+// ReadOption configures how NumPy data files are read.
+type ReadOption func(*readConfig)
+
+type readConfig struct {
+	maxHeader int    // maximum header size, in bytes.
+	maxElems  int    // maximum number of array elements (0: no limit).
+	maxBytes  int64  // maximum array data size, in bytes (0: no limit).
+	workers   int    // number of goroutines used by ReadAt (0: GOMAXPROCS).
+	layout    Layout // layout of Fortran-ordered data read into slices and arrays.
+}
+
+func newReadConfig(opts []ReadOption) readConfig {
+	cfg := readConfig{
+		maxHeader: DefaultMaxHeaderBytes,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithMaxHeaderBytes sets the maximum size, in bytes, of the header of
+// the NumPy data file.
+// The default is DefaultMaxHeaderBytes.
+// A value of zero or less disables the limit.
+func WithMaxHeaderBytes(n int) ReadOption {
+	return func(cfg *readConfig) {
+		cfg.maxHeader = n
+	}
+}
+
+// WithMaxElements sets the maximum number of elements of the array
+// described by the header of the NumPy data file.
+// By default, or with a value of zero or less, the number of elements is
+// not limited.
+func WithMaxElements(n int) ReadOption {
+	return func(cfg *readConfig) {
+		cfg.maxElems = n
+	}
+}
+
+// WithMaxBytes sets the maximum size, in bytes, of the array data of
+// the NumPy data file.
+// By default, or with a value of zero or less, the array data size is
+// not limited.
+func WithMaxBytes(n int64) ReadOption {
+	return func(cfg *readConfig) {
+		cfg.maxBytes = n
+	}
+}
+
 // Reader reads data from a NumPy data file.
 type Reader struct {
-	r   io.Reader
-	err error // last error
+	r     io.Reader
+	err   error // last error
+	cfg   readConfig
+	avail int64 // size of the data following the header, in bytes (-1: unknown)
+
+	descr  *ArrayDescr // data type of the array elements, once parsed.
+	adescr *ArrayDescr // description of the arrays read into an Array, once resolved.
+	gdt    *dType      // Go type of the array elements, once resolved.
+	gerr   error       // error resolving the Go type of the array elements.
 
 	Header Header
 	order  binary.ByteOrder
 }
 
 // NewReader creates a new NumPy data file format reader.
-func NewReader(r io.Reader) (*Reader, error) {
-	rr := &Reader{r: r}
+//
+// The memory used to decode the array is bounded by the WithMaxElements
+// and WithMaxBytes options and, when it is known, by the size of the
+// remaining data of r.
+func NewReader(r io.Reader, opts ...ReadOption) (*Reader, error) {
+	rr := &Reader{r: r, cfg: newReadConfig(opts), avail: -1}
 	rr.readHeader()
 	if rr.err != nil {
 		return nil, rr.err
 	}
+	if n, ok := remaining(r); ok {
+		rr.avail = n
+	}
 	return rr, rr.err
 }
 
@@ -67,10 +189,14 @@ func (r *Reader) readHeader() {
 	var magic [6]byte
 	r.readAny(&magic)
 	if r.err != nil {
+		if r.err != io.EOF {
+			// an empty stream is reported as io.EOF.
+			r.err = r.headerError(0, "could not read magic string", r.err)
+		}
 		return
 	}
 	if magic != Magic {
-		r.err = ErrInvalidNumPyFormat
+		r.err = &HeaderError{Offset: 0, Reason: fmt.Sprintf("invalid magic string %q", magic[:])}
 		return
 	}
 
@@ -78,86 +204,190 @@ func (r *Reader) readHeader() {
 
 	r.readAny(&r.Header.Major)
 	r.readAny(&r.Header.Minor)
+	if r.err != nil {
+		r.err = r.headerError(int64(len(Magic)), "could not read version numbers", r.err)
+		return
+	}
 	switch r.Header.Major {
 	case 1:
 		var v uint16
 		r.readAny(&v)
 		hdrLen = int(v)
+		r.Header.DataOffset = int64(len(Magic) + 2 + 2 + hdrLen)
 	case 2:
 		var v uint32
 		r.readAny(&v)
 		hdrLen = int(v)
+		r.Header.DataOffset = int64(len(Magic) + 2 + 4 + hdrLen)
 	default:
-		r.err = fmt.Errorf("npy: invalid major version number (%d)", r.Header.Major)
+		r.err = &HeaderError{
+			Offset: int64(len(Magic)),
+			Reason: fmt.Sprintf("invalid major version number (%d)", r.Header.Major),
+		}
+		return
 	}
 
 	if r.err != nil {
+		r.err = r.headerError(int64(len(Magic)+2), "could not read header length", r.err)
+		return
+	}
+
+	if lim := r.cfg.maxHeader; lim > 0 && hdrLen > lim {
+		r.err = &LimitError{Resource: "header size", Size: int64(hdrLen), Limit: int64(lim)}
 		return
 	}
 
 	hdr := make([]byte, hdrLen)
 	r.readAny(&hdr)
-	idx := bytes.LastIndexByte(hdr, '\n')
-	hdr = hdr[:idx]
+	if r.err != nil {
+		r.err = r.headerError(r.Header.DataOffset-int64(hdrLen), "could not read header", r.err)
+		return
+	}
 	r.readDescr(hdr)
 }
 
+// headerError returns a HeaderError for the provided error err, that
+// occurred while reading the header field at the provided offset.
+// io.EOF errors are reported as io.ErrUnexpectedEOF.
+func (r *Reader) headerError(off int64, reason string, err error) *HeaderError {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &HeaderError{Offset: off, Reason: reason, Err: err}
+}
+
 func (r *Reader) readDescr(buf []byte) {
 	if r.err != nil {
 		return
 	}
 
-	var (
-		descrKey = []byte("'descr': ")
-		orderKey = []byte("'fortran_order': ")
-		shapeKey = []byte("'shape': ")
-		trailer  = []byte(", ")
-	)
+	// offset of the header dictionary.
+	off := r.Header.DataOffset - int64(len(buf))
 
-	begDescr := bytes.Index(buf, descrKey)
-	begOrder := bytes.Index(buf, orderKey)
-	begShape := bytes.Index(buf, shapeKey)
-	endDescr := bytes.Index(buf, []byte("}"))
-	if begDescr < 0 || begOrder < 0 || begShape < 0 {
-		r.err = fmt.Errorf("npy: invalid dictionary format")
+	v, err := parsePyLiteral(string(buf))
+	if err != nil {
+		r.err = &HeaderError{Offset: off, Reason: "invalid dictionary", Err: err}
+		return
+	}
+	dict, ok := v.(*py.Dict)
+	if !ok {
+		r.err = &HeaderError{Offset: off, Reason: fmt.Sprintf("invalid header type %T", v)}
 		return
 	}
 
-	descr := string(buf[begDescr+len(descrKey)+1 : begOrder-len(trailer)-1])
-	order := string(buf[begOrder+len(orderKey) : begShape-len(trailer)])
-	shape := buf[begShape+len(shapeKey) : endDescr-len(trailer)]
+	descr, ok := dict.Get("descr")
+	if !ok {
+		r.err = &HeaderError{Offset: off, Reason: "missing 'descr' key"}
+		return
+	}
+	switch descr := descr.(type) {
+	case string:
+		r.Header.Descr.Type = descr
+	default:
+		dt, err := newDescrFromLiteral(descr)
+		if err != nil {
+			r.err = &HeaderError{Offset: off, Reason: "invalid 'descr' value", Err: err}
+			return
+		}
+		r.Header.Descr.Type = dt.Descr()
+	}
 
-	r.Header.Descr.Type = descr // FIXME(sbinet): better handling
-	switch order {
-	case "False":
-		r.Header.Descr.Fortran = false
-	case "True":
-		r.Header.Descr.Fortran = true
+	order, ok := dict.Get("fortran_order")
+	if !ok {
+		r.err = &HeaderError{Offset: off, Reason: "missing 'fortran_order' key"}
+		return
+	}
+	switch order := order.(type) {
+	case bool:
+		r.Header.Descr.Fortran = order
 	default:
-		r.err = fmt.Errorf("npy: invalid 'fortran_order' value (%v)", order)
+		r.err = &HeaderError{Offset: off, Reason: fmt.Sprintf("invalid 'fortran_order' value (%v)", order)}
 		return
 	}
 
-	if string(shape) == "()" {
-		r.Header.Descr.Shape = nil
+	shape, ok := dict.Get("shape")
+	if !ok {
+		r.err = &HeaderError{Offset: off, Reason: "missing 'shape' key"}
 		return
 	}
-
-	shape = shape[1 : len(shape)-1]
-	toks := strings.Split(string(shape), ",")
-	for _, tok := range toks {
-		tok = strings.TrimSpace(tok)
-		if tok == "" {
-			continue
+	tup, ok := shape.(*py.Tuple)
+	if !ok {
+		r.err = &HeaderError{Offset: off, Reason: fmt.Sprintf("invalid 'shape' value (%v)", shape)}
+		return
+	}
+	r.Header.Descr.Shape = nil
+	for i := 0; i < tup.Len(); i++ {
+		dim, ok := tup.Get(i).(int)
+		if !ok || dim < 0 {
+			r.err = &HeaderError{Offset: off, Reason: fmt.Sprintf("invalid 'shape' value (%v)", shape), Err: ErrShapeMismatch}
+			return
 		}
-		i, err := strconv.Atoi(tok)
+		r.Header.Descr.Shape = append(r.Header.Descr.Shape, dim)
+	}
+
+	n, err := shapeSize(r.Header.Descr.Shape)
+	if err != nil {
+		r.err = &HeaderError{Offset: off, Reason: "invalid 'shape' value", Err: err}
+		return
+	}
+	if lim := r.cfg.maxElems; lim > 0 && n > lim {
+		r.err = &LimitError{Resource: "number of elements", Size: int64(n), Limit: int64(lim)}
+		return
+	}
+}
+
+// dtype returns the data type of the array elements, as described by the
+// header.
+func (r *Reader) dtype() (*ArrayDescr, error) {
+	if r.descr == nil {
+		dt, err := r.Header.Dtype()
 		if err != nil {
-			r.err = err
-			return
+			return nil, err
 		}
-		r.Header.Descr.Shape = append(r.Header.Descr.Shape, int(i))
+		r.descr = dt
+	}
+	return r.descr, nil
+}
+
+// goDtype returns the Go type of the array elements, as described by the
+// header.
+func (r *Reader) goDtype() (dType, error) {
+	if r.gdt == nil {
+		dt, err := newDtype(r.Header.Descr.Type)
+		r.gdt, r.gerr = &dt, err
+	}
+	return *r.gdt, r.gerr
+}
+
+// arrayDescr returns the description of the array read into an Array,
+// given the Go type of its elements.
+func (r *Reader) arrayDescr(dt dType) (*ArrayDescr, error) {
+	if r.adescr != nil {
+		return r.adescr, nil
+	}
+
+	const flags = 0
+	descr, err := newDescrFrom(r.Header.Descr.Type, flags)
+	if err != nil && dt.rt == voidType {
+		// e.g. structured data types.
+		var v *ArrayDescr
+		v, err = r.dtype()
+		if err == nil {
+			cpy := *v
+			descr = &cpy
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create array description from %q: %w", r.Header.Descr.Type, err)
 	}
 
+	if descr.esize < 0 {
+		// FIXME(sbinet): shouldn't this be addressed in newDescrFrom ?
+		// check with c-numpy. (for dtype="|O")
+		descr.esize = dt.size
+	}
+	r.adescr = descr
+	return r.adescr, nil
 }
 
 // Read reads the numpy-array data from the underlying NumPy file.
@@ -170,6 +400,66 @@ func (r *Reader) Read(ptr interface{}) error {
 		return r.err
 	}
 
+	cr := &countReader{r: r.r}
+	r.r = cr
+	defer func() {
+		r.r = cr.r
+	}()
+
+	err := r.readError(ptr, r.readLayoutValue(ptr), cr.n, cr.err)
+	if r.err != nil {
+		// keep the typed error for subsequent calls.
+		r.err = err
+	}
+	return err
+}
+
+// readLayoutValue reads the numpy-array data into the provided pointed at
+// value, with the elements laid out as configured.
+func (r *Reader) readLayoutValue(ptr interface{}) error {
+	if rv := reflect.ValueOf(ptr); rv.IsValid() && rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		if ok, err := r.readLayout(rv.Elem()); ok {
+			return err
+		}
+	}
+
+	return r.readValue(ptr)
+}
+
+// Payload returns the array data as stored in the underlying NumPy file,
+// without decoding it.
+// The returned reader starts right after the header and yields exactly
+// Header.DataSize bytes.
+// For object ('O') arrays, whose pickled data size is not known in
+// advance, the returned reader yields the rest of the underlying stream.
+//
+// Payload and Read consume the same underlying stream: only one of them
+// should be used.
+func (r *Reader) Payload() io.Reader {
+	if r.err != nil {
+		return errReader{r.err}
+	}
+	dt, err := r.dtype()
+	if err != nil {
+		return errReader{err}
+	}
+	if dt.kind == 'O' {
+		return r.r
+	}
+	size, err := r.Header.DataSize()
+	if err != nil {
+		return errReader{err}
+	}
+	return io.LimitReader(r.r, size)
+}
+
+// readValue reads the numpy-array data into the provided pointed at value,
+// with the elements of flat slices and arrays laid out as stored.
+func (r *Reader) readValue(ptr interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
 	rv := reflect.ValueOf(ptr)
 	if !rv.IsValid() || rv.Kind() != reflect.Ptr {
 		return errNotPtr
@@ -179,28 +469,53 @@ func (r *Reader) Read(ptr interface{}) error {
 		return errNilPtr
 	}
 
+	if t, ok := ptr.(tensorReader); ok {
+		return t.readTensor(r)
+	}
+
 	nelems := numElems(r.Header.Descr.Shape)
-	dt, err := newDtype(r.Header.Descr.Type)
-	if err != nil {
-		return err
+	dt, err := r.goDtype()
+	switch ptr.(type) {
+	case *Array, *big.Float, *[]*big.Float, *BigComplex, *[]BigComplex:
+		// data types without a Go type (e.g. float16 or longdouble) are
+		// read through their array description.
+	default:
+		var derr *DescrError
+		switch {
+		case errors.As(err, &derr):
+			return err
+		case err != nil:
+			return ErrTypeMismatch
+		}
+	}
+
+	if err == nil {
+		r.order = dt.order
+		if dt.rt != anyType {
+			err = r.checkSize(nelems, dt.size)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if dt.rt == anyType {
+		if _, ok := ptr.(*Array); !ok {
+			return r.readObject(rv.Elem())
+		}
 	}
-	r.order = dt.order
 
 	switch vptr := ptr.(type) {
 	case *int, *uint, *[]int, *[]uint:
 		return ErrInvalidType
 
+	case *big.Float, *[]*big.Float, *BigComplex, *[]BigComplex:
+		return r.readExtended(ptr, nelems)
+
 	case *Array:
-		const flags = 0
-		descr, err := newDescrFrom(r.Header.Descr.Type, flags)
+		descr, err := r.arrayDescr(dt)
 		if err != nil {
-			return fmt.Errorf("could not create array description from %q: %w", r.Header.Descr.Type, err)
-		}
-
-		if descr.esize < 0 {
-			// FIXME(sbinet): shouldn't this be addressed in newDescrFrom ?
-			// check with c-numpy. (for dtype="|O")
-			descr.esize = dt.size
+			return err
 		}
 
 		vptr.descr = *descr
@@ -212,14 +527,14 @@ func (r *Reader) Read(ptr interface{}) error {
 			return fmt.Errorf("could not setup array strides for %q: %w", r.Header.Descr.Type, err)
 		}
 
-		raw, err := io.ReadAll(r.r)
+		raw, err := r.readPayload(nelems, descr)
 		if err != nil {
-			return fmt.Errorf("could not consume all data: %w", err)
+			return err
 		}
 
 		data, err := vptr.descr.unmarshal(raw, r.Header.Descr.Shape)
 		if err != nil {
-			return fmt.Errorf("could not unmarshal array data: %w", err)
+			return err
 		}
 
 		if vptr.descr.kind == 'O' {
@@ -236,7 +551,7 @@ func (r *Reader) Read(ptr interface{}) error {
 
 	case *mat.Dense:
 		var data []float64
-		err := r.Read(&data)
+		err := r.readValue(&data)
 		if err != nil && !errors.Is(err, io.EOF) {
 			r.err = err
 			return r.err
@@ -246,6 +561,10 @@ func (r *Reader) Read(ptr interface{}) error {
 			r.err = err
 			return r.err
 		}
+		if nrows == 0 || ncols == 0 {
+			*vptr = mat.Dense{}
+			return r.err
+		}
 		if r.Header.Descr.Fortran {
 			*vptr = *mat.NewDense(nrows, ncols, nil)
 			i := 0
@@ -260,6 +579,98 @@ func (r *Reader) Read(ptr interface{}) error {
 		}
 		return r.err
 
+	case *[]mat.Dense:
+		var data []float64
+		err := r.readValue(&data)
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.err = err
+			return r.err
+		}
+		shape := r.Header.Descr.Shape
+		if len(shape) != 3 {
+			return fmt.Errorf("npy: array shape not supported %v (want (batch, rows, cols)): %w", shape, ErrShapeMismatch)
+		}
+		if r.Header.Descr.Fortran {
+			data = fortranToC(data, shape)
+		}
+		var (
+			nmats = shape[0]
+			nrows = shape[1]
+			ncols = shape[2]
+			size  = nrows * ncols
+		)
+		*vptr = make([]mat.Dense, nmats)
+		if size == 0 {
+			return r.err
+		}
+		for i := range *vptr {
+			(*vptr)[i] = *mat.NewDense(nrows, ncols, data[i*size:(i+1)*size:(i+1)*size])
+		}
+		return r.err
+
+	case *mat.VecDense:
+		if len(r.Header.Descr.Shape) != 1 {
+			return fmt.Errorf("npy: array shape %v not supported for *mat.VecDense: %w", r.Header.Descr.Shape, ErrShapeMismatch)
+		}
+		var data []float64
+		err := r.readValue(&data)
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.err = err
+			return r.err
+		}
+		if len(data) == 0 {
+			*vptr = mat.VecDense{}
+			return r.err
+		}
+		*vptr = *mat.NewVecDense(len(data), data)
+		return r.err
+
+	case *mat.CDense:
+		var data []complex128
+		switch dt.rt {
+		case complex128Type:
+			err := r.readValue(&data)
+			if err != nil && !errors.Is(err, io.EOF) {
+				r.err = err
+				return r.err
+			}
+		case complex64Type:
+			var c64 []complex64
+			err := r.readValue(&c64)
+			if err != nil && !errors.Is(err, io.EOF) {
+				r.err = err
+				return r.err
+			}
+			data = make([]complex128, len(c64))
+			for i, v := range c64 {
+				data[i] = complex128(v)
+			}
+		default:
+			return ErrTypeMismatch
+		}
+		nrows, ncols, err := dimsFromShape(r.Header.Descr.Shape)
+		if err != nil {
+			r.err = err
+			return r.err
+		}
+		if nrows == 0 || ncols == 0 {
+			*vptr = mat.CDense{}
+			return r.err
+		}
+		if r.Header.Descr.Fortran {
+			*vptr = *mat.NewCDense(nrows, ncols, nil)
+			i := 0
+			for icol := 0; icol < ncols; icol++ {
+				for irow := 0; irow < nrows; irow++ {
+					vptr.Set(irow, icol, data[i])
+					i++
+				}
+			}
+		} else {
+			*vptr = *mat.NewCDense(nrows, ncols, data)
+		}
+		return r.err
+
 	case *bool:
 		if dt.rt != boolType {
 			return ErrTypeMismatch
@@ -707,51 +1118,84 @@ func (r *Reader) Read(ptr interface{}) error {
 		}
 		return r.err
 
+	case *[][]byte:
+		switch {
+		case dt.rt == voidType:
+			// opaque elements are returned as is.
+		case dt.rt == stringType && !dt.utf:
+			// byte strings are returned without their NUL padding.
+		default:
+			return ErrTypeMismatch
+		}
+		raw := make([]byte, nelems*dt.size)
+		_, err := io.ReadFull(r.r, raw)
+		if err != nil {
+			r.err = err
+			return r.err
+		}
+		*vptr = make([][]byte, nelems)
+		for i := range *vptr {
+			v := raw[i*dt.size : (i+1)*dt.size : (i+1)*dt.size]
+			if dt.rt == stringType {
+				v = bytes.TrimRight(v, "\x00")
+			}
+			(*vptr)[i] = v
+		}
+		return r.err
+
 	case *string:
 		if dt.rt != stringType {
 			return ErrTypeMismatch
 		}
+		descr := dt.stringDescr()
+		raw := make([]byte, descr.esize)
+		_, err := r.read(raw)
+		if err != nil {
+			return err
+		}
+		*vptr, err = descr.decodeString(raw)
+		if err != nil {
+			r.err = &decodeError{elem: 0, err: fmt.Errorf("npy: could not decode string: %w", err)}
+		}
+		return r.err
 
-		switch {
-		case dt.utf:
-			raw, err := io.ReadAll(io.LimitReader(r.r, utf8.UTFMax*int64(dt.size)))
-			if err != nil {
-				r.err = err
-				return r.err
-			}
-			var str string
-			for len(raw) > 0 {
-				r, size := utf8.DecodeRune(raw)
-				str += string(r)
-				raw = raw[size:]
-			}
-			*vptr = str
+	case *[]string:
+		if dt.rt != stringType {
+			return ErrTypeMismatch
+		}
+		descr := dt.stringDescr()
+		raw, err := r.readPayload(nelems, descr)
+		if err != nil {
+			r.err = err
 			return r.err
-
-		case !dt.utf:
-			buf, err := io.ReadAll(io.LimitReader(r.r, int64(dt.size)))
+		}
+		*vptr = make([]string, nelems)
+		for i := range *vptr {
+			(*vptr)[i], err = descr.decodeString(raw[i*descr.esize : (i+1)*descr.esize])
 			if err != nil {
-				r.err = err
+				r.err = &decodeError{elem: i, err: fmt.Errorf("npy: could not decode string element %d: %w", i, err)}
 				return r.err
 			}
-			n := bytes.Index(buf, []byte{0})
-			if n > 0 {
-				buf = buf[:n]
-			}
-			*vptr = string(buf)
-			return r.err
 		}
+		return r.err
 	}
 
 	rv = reflect.Indirect(rv)
+	if dt.rt == voidType {
+		return r.readVoid(rv, nelems, dt)
+	}
+
 	switch rv.Kind() {
 	case reflect.Slice:
 		rv.SetLen(0)
 		elt := rv.Type().Elem()
+		if !dt.rt.ConvertibleTo(elt) {
+			return errNoConv
+		}
 		v := reflect.New(dt.rt).Elem()
 		slice := rv
 		for i := 0; i < nelems; i++ {
-			err := r.Read(v.Addr().Interface())
+			err := r.readValue(v.Addr().Interface())
 			if err != nil && !errors.Is(err, io.EOF) {
 				r.err = err
 				return r.err
@@ -763,13 +1207,16 @@ func (r *Reader) Read(ptr interface{}) error {
 
 	case reflect.Array:
 		if nelems > rv.Type().Len() {
-			return errDims
+			return ErrShapeMismatch
 		}
 
 		elt := rv.Type().Elem()
+		if !dt.rt.ConvertibleTo(elt) {
+			return errNoConv
+		}
 		v := reflect.New(dt.rt).Elem()
 		for i := 0; i < nelems; i++ {
-			err := r.Read(v.Addr().Interface())
+			err := r.readValue(v.Addr().Interface())
 			if err != nil && !errors.Is(err, io.EOF) {
 				r.err = err
 				return r.err
@@ -807,13 +1254,30 @@ func (r *Reader) Read(ptr interface{}) error {
 	panic("unreachable")
 }
 
+// readObject decodes the pickled content of an object array into the
+// provided value.
+func (r *Reader) readObject(rv reflect.Value) error {
+	var arr Array
+	err := r.readValue(&arr)
+	if err != nil {
+		return err
+	}
+
+	data, err := arr.Native()
+	if err != nil {
+		return fmt.Errorf("npy: could not convert object array: %w", err)
+	}
+
+	return decodeNative(rv, data)
+}
+
 func dimsFromShape(shape []int) (int, int, error) {
 	nrows := 0
 	ncols := 0
 
 	switch len(shape) {
 	default:
-		return -1, -1, fmt.Errorf("npy: array shape not supported %v", shape)
+		return -1, -1, fmt.Errorf("npy: array shape not supported %v: %w", shape, ErrShapeMismatch)
 
 	case 0:
 		nrows = 1
@@ -844,9 +1308,154 @@ func (r *Reader) read(p []byte) (int, error) {
 	}
 	var n int
 	n, r.err = io.ReadFull(r.r, p)
+	if r.err == io.EOF {
+		// the header describes more array data.
+		r.err = io.ErrUnexpectedEOF
+	}
 	return n, r.err
 }
 
+// readVoid reads the opaque elements of the array data into the provided
+// [][n]byte slice or [N][n]byte array, where n is the size of an element.
+func (r *Reader) readVoid(rv reflect.Value, nelems int, dt dType) error {
+	rt := rv.Type()
+	if (rt.Kind() != reflect.Slice && rt.Kind() != reflect.Array) ||
+		rt.Elem().Kind() != reflect.Array ||
+		rt.Elem().Elem().Kind() != reflect.Uint8 ||
+		rt.Elem().Len() != dt.size {
+		return ErrTypeMismatch
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		rv.Set(reflect.MakeSlice(rt, nelems, nelems))
+	case reflect.Array:
+		if nelems > rv.Len() {
+			return ErrShapeMismatch
+		}
+	}
+
+	raw := make([]byte, nelems*dt.size)
+	_, err := io.ReadFull(r.r, raw)
+	if err != nil {
+		r.err = err
+		return r.err
+	}
+	for i := 0; i < nelems; i++ {
+		reflect.Copy(rv.Index(i), reflect.ValueOf(raw[i*dt.size:(i+1)*dt.size]))
+	}
+	return nil
+}
+
+// checkSize checks the size of the array data against the configured
+// limits and, when it is known, against the size of the data following
+// the header in the underlying reader.
+// checkSize must be called before allocating memory for the array data.
+func (r *Reader) checkSize(nelems, esize int) error {
+	if esize > 0 && nelems > math.MaxInt/esize {
+		return &LimitError{Resource: fmt.Sprintf("array data size (%d*%d bytes)", nelems, esize), Size: -1, Limit: math.MaxInt}
+	}
+	size := int64(nelems) * int64(esize)
+	if lim := r.cfg.maxBytes; lim > 0 && size > lim {
+		return &LimitError{Resource: "array data size", Size: size, Limit: lim}
+	}
+	if esize == 0 && r.avail >= 0 && int64(nelems) > r.avail {
+		// zero-sized elements hold no data, but each of them is still
+		// decoded into a Go value.
+		return &LimitError{Resource: "number of zero-sized elements", Size: int64(nelems), Limit: r.avail}
+	}
+	if r.avail >= 0 && size > r.avail {
+		return r.payloadError(r.avail, fmt.Errorf(
+			"npy: array data size (%d bytes) exceeds available data (%d bytes): %w",
+			size, r.avail, io.ErrUnexpectedEOF,
+		))
+	}
+	return nil
+}
+
+// remaining returns the number of bytes left in the provided reader, if
+// it is known.
+func remaining(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case *countReader:
+		return remaining(r.r)
+	case *io.LimitedReader:
+		if n, ok := remaining(r.R); ok && n < r.N {
+			return n, true
+		}
+		return max(r.N, 0), true
+	case interface{ Len() int }:
+		// bytes.Reader, bytes.Buffer, strings.Reader, ...
+		return int64(r.Len()), true
+	case io.Seeker:
+		cur, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		_, err = r.Seek(cur, io.SeekStart)
+		if err != nil || end < cur {
+			return 0, false
+		}
+		return end - cur, true
+	}
+	return 0, false
+}
+
+// readPayload reads the array data, as described by the provided data type.
+func (r *Reader) readPayload(nelems int, descr *ArrayDescr) ([]byte, error) {
+	if descr.kind == 'O' {
+		// pickled data: the payload size is not known in advance.
+		rr := r.r
+		if lim := r.cfg.maxBytes; lim > 0 {
+			rr = io.LimitReader(rr, lim+1)
+		}
+		raw, err := io.ReadAll(rr)
+		if err != nil {
+			return nil, fmt.Errorf("could not consume all data: %w", err)
+		}
+		if lim := r.cfg.maxBytes; lim > 0 && int64(len(raw)) > lim {
+			return nil, &LimitError{Resource: "array data size", Size: -1, Limit: lim}
+		}
+		return raw, nil
+	}
+
+	err := r.checkSize(nelems, descr.esize)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(nelems * descr.esize)
+	raw, err := io.ReadAll(io.LimitReader(r.r, size))
+	if err != nil {
+		return nil, fmt.Errorf("could not consume all data: %w", err)
+	}
+	if int64(len(raw)) != size {
+		return nil, fmt.Errorf("could not read array data (got=%d bytes, want=%d bytes): %w", len(raw), size, io.ErrUnexpectedEOF)
+	}
+	return raw, nil
+}
+
+// shapeSize returns the number of elements of an array with the provided
+// shape.
+// shapeSize returns an error if a dimension is negative or if the number
+// of elements overflows an int.
+func shapeSize(shape []int) (int, error) {
+	n := 1
+	for i, dim := range shape {
+		if dim < 0 {
+			return 0, fmt.Errorf("npy: invalid shape[%d]=%d: %w", i, dim, ErrShapeMismatch)
+		}
+		if dim != 0 && n > math.MaxInt/dim {
+			return 0, fmt.Errorf("npy: number of elements of shape %v overflows: %w", shape, ErrShapeMismatch)
+		}
+		n *= dim
+	}
+	return n, nil
+}
+
 func numElems(shape []int) int {
 	n := 1
 	for _, v := range shape {
@@ -903,6 +1512,32 @@ func stringLen(dtype string) (int, error) {
 	return 0, fmt.Errorf("npy: %q is not a string-like dtype", dtype)
 }
 
+// countReader counts the number of bytes read from r, and records the
+// last error returned by r.
+type countReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// errReader is an io.Reader that always returns err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
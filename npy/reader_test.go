@@ -6,12 +6,19 @@ package npy
 
 import (
 	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/sbinet/npyio/npy/float16"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -199,6 +206,55 @@ func TestReaderSlice(t *testing.T) {
 	}
 }
 
+func TestTypeFrom(t *testing.T) {
+	for _, tc := range []struct {
+		dtype string
+		want  reflect.Type
+	}{
+		{"<f8", reflect.TypeOf(float64(0))},
+		{"|V8", reflect.TypeOf([]byte(nil))},
+		{"[('x', '<f8'), ('y', '<i4')]", reflect.TypeOf([]byte(nil))},
+		{"(2,)<f8", reflect.TypeOf([]byte(nil))},
+		{"<f2", nil},
+		{"<f16", nil},
+		{"<c32", nil},
+		{"<x4", nil},
+	} {
+		t.Run(tc.dtype, func(t *testing.T) {
+			if got, want := TypeFrom(tc.dtype), tc.want; got != want {
+				t.Fatalf("invalid type:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+
+	t.Run("read-float16", func(t *testing.T) {
+		f16s, err := NewArray(mustParseDescr(t, "<f2"), []int{2}, []float16.Num{float16.New(1), float16.New(2)})
+		if err != nil {
+			t.Fatalf("could not create array: %+v", err)
+		}
+		buf := new(bytes.Buffer)
+		err = Write(buf, f16s)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+		raw := buf.Bytes()
+
+		err = Read(bytes.NewReader(raw), new([]float32))
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrTypeMismatch)
+		}
+
+		var arr Array
+		err = Read(bytes.NewReader(raw), &arr)
+		if err != nil {
+			t.Fatalf("could not read array: %+v", err)
+		}
+		if got, want := arr.Data(), []float16.Num{float16.New(1), float16.New(2)}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+		}
+	})
+}
+
 func TestReaderNDimSlice(t *testing.T) {
 	want := make([]float64, 2*3*4)
 	for i := range want {
@@ -434,3 +490,645 @@ func TestRaggedArray(t *testing.T) {
 		t.Fatalf("invalid ragged-array:\ngot= %v\nwant=%v", got, want)
 	}
 }
+
+func TestRaggedArrayDecode(t *testing.T) {
+	for _, tc := range []struct {
+		fname string
+		ptr   any
+		want  any
+		err   error
+	}{
+		{
+			fname: "../testdata/ragged-array.npy",
+			ptr:   new([][]float64),
+			want:  &[][]float64{{1, 2, 3, 4}, {5, 6, 7}, {8, 9}},
+		},
+		{
+			fname: "../testdata/ragged-array.npy",
+			ptr:   new([][]int16),
+			want:  &[][]int16{{1, 2, 3, 4}, {5, 6, 7}, {8, 9}},
+		},
+		{
+			fname: "../testdata/ragged-array.npy",
+			ptr:   new([]any),
+			want:  &[]any{[]any{1, 2, 3, 4}, []any{5, 6, 7}, []any{8, 9}},
+		},
+		{
+			fname: "../testdata/ragged-array-mixed.npy",
+			ptr:   new([][]any),
+			want:  &[][]any{{1, "2", 3, "4"}, {5, "six", 7}, {"8", 9}},
+		},
+		{
+			fname: "../testdata/ragged-array-mixed.npy",
+			ptr:   new([][]float64),
+			err:   ErrTypeMismatch,
+		},
+		{
+			fname: "../testdata/ragged-array.npy",
+			ptr:   new([][]string),
+			err:   ErrTypeMismatch,
+		},
+		{
+			fname: "../testdata/ragged-array.npy",
+			ptr:   new([]float64),
+			err:   ErrTypeMismatch,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			f, err := os.Open(tc.fname)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			err = Read(f, tc.ptr)
+			switch {
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", err, tc.err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("could not read ragged array: %+v", err)
+			}
+
+			if got, want := tc.ptr, tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid ragged-array:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestReaderLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hdr  string
+		data []byte
+		opts []ReadOption
+		ptr  any // destination (default: *Array)
+		err  error
+	}{
+		{
+			name: "max-header",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2,), }",
+			opts: []ReadOption{WithMaxHeaderBytes(32)},
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "default-max-header",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2,), }" + strings.Repeat(" ", DefaultMaxHeaderBytes),
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "max-elements",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }",
+			opts: []ReadOption{WithMaxElements(5)},
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "max-bytes",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }",
+			opts: []ReadOption{WithMaxBytes(47)},
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "max-bytes-object",
+			hdr:  "{'descr': '|O', 'fortran_order': False, 'shape': (2, 3), }",
+			data: make([]byte, 128),
+			opts: []ReadOption{WithMaxBytes(64)},
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "no-limits-large-array",
+			hdr:  "{'descr': '<c16', 'fortran_order': False, 'shape': (268435457,), }",
+			ptr:  new([]complex128),
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "no-limits-overflow",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (4611686018427387904,), }",
+			ptr:  new([]float64),
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "no-limits-short-data",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (4611686018427387,), }",
+			data: make([]byte, 64),
+			ptr:  new([]float64),
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "zero-sized-elements",
+			hdr:  "{'descr': '|V0', 'fortran_order': False, 'shape': (1073741824,), }",
+			ptr:  new([][]byte),
+			err:  ErrLimitExceeded,
+		},
+		{
+			name: "negative-dim",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2, -3), }",
+			err:  ErrShapeMismatch,
+		},
+		{
+			name: "overflow",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (4294967296, 4294967296), }",
+			err:  ErrShapeMismatch,
+		},
+		{
+			name: "truncated",
+			hdr:  "{'descr': '<i2', 'fortran_order': False, 'shape': (2, 3), }",
+			data: make([]byte, 11),
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "pickle-length",
+			hdr:  "{'descr': '|O', 'fortran_order': False, 'shape': (2,), }",
+			data: []byte("\x80\x03B\xff\xff\xff\x7f."),
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "ok",
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }",
+			data: make([]byte, 48),
+			opts: []ReadOption{WithMaxHeaderBytes(128), WithMaxElements(6), WithMaxBytes(48)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			buf.Write(Magic[:])
+			buf.Write([]byte{2, 0})
+			_ = binary.Write(buf, binary.LittleEndian, uint32(len(tc.hdr)+1))
+			buf.WriteString(tc.hdr + "\n")
+			buf.Write(tc.data)
+
+			ptr := tc.ptr
+			if ptr == nil {
+				ptr = new(Array)
+			}
+			err := Read(buf, ptr, tc.opts...)
+			switch {
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", err, tc.err)
+				}
+			case err != nil:
+				t.Fatalf("could not read array: %+v", err)
+			}
+		})
+	}
+}
+
+func TestReaderHeader(t *testing.T) {
+	for _, tc := range []struct {
+		hdr  string
+		want Header
+		err  bool
+	}{
+		{
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }",
+			want: newTestHeader("<f8", false, []int{2, 3}),
+		},
+		{
+			hdr:  `{"shape": (), "fortran_order": True, "descr": ">i4"}`,
+			want: newTestHeader(">i4", true, nil),
+		},
+		{
+			hdr:  "{'descr': [('x', '<f8'), ('y', '<i4', (2,))], 'fortran_order': False, 'shape': (5,), }",
+			want: newTestHeader("[('x', '<f8'), ('y', '<i4', (2,))]", false, []int{5}),
+		},
+		{
+			hdr:  "{'descr': '<f8', 'fortran_order': False, 'shape': (3L, 2L), }",
+			want: newTestHeader("<f8", false, []int{3, 2}),
+		},
+		{hdr: "", err: true},
+		{hdr: "{", err: true},
+		{hdr: "[1, 2]", err: true},
+		{hdr: "{'descr': '<f8', 'shape': (2, 3), }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': False, }", err: true},
+		{hdr: "{'fortran_order': False, 'shape': (2, 3), }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': 0, 'shape': (2, 3), }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': False, 'shape': [2, 3], }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': False, 'shape': (2), }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 'x'), }", err: true},
+		{hdr: "{'descr': 42, 'fortran_order': False, 'shape': (2, 3), }", err: true},
+		{hdr: "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), } trailing", err: true},
+	} {
+		t.Run(tc.hdr, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			buf.Write(Magic[:])
+			buf.Write([]byte{1, 0})
+			_ = binary.Write(buf, binary.LittleEndian, uint16(len(tc.hdr)+1))
+			buf.WriteString(tc.hdr + "\n")
+
+			r, err := NewReader(buf)
+			switch {
+			case tc.err:
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			case err != nil:
+				t.Fatalf("could not read header: %+v", err)
+			}
+
+			tc.want.DataOffset = int64(len(Magic) + 4 + len(tc.hdr) + 1)
+			if got, want := r.Header, tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid header:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+func newTestHeader(descr string, fortran bool, shape []int) Header {
+	hdr := Header{Major: 1}
+	hdr.Descr.Type = descr
+	hdr.Descr.Fortran = fortran
+	hdr.Descr.Shape = shape
+	return hdr
+}
+
+func fuzzSeeds(f *testing.F) {
+	fnames, err := filepath.Glob("../testdata/*.npy")
+	if err != nil {
+		f.Fatalf("could not find seed files: %+v", err)
+	}
+	for _, fname := range fnames {
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			f.Fatalf("could not read seed file: %+v", err)
+		}
+		f.Add(raw)
+	}
+}
+
+func FuzzNewReader(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		_, _ = NewReader(bytes.NewReader(raw), WithMaxElements(1<<16), WithMaxBytes(1<<20))
+	})
+}
+
+func FuzzReaderRead(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		for _, ptr := range []any{
+			new(Array),
+			new(any),
+			new(bool),
+			new(float64),
+			new(string),
+			new([]int16),
+			new([]float64),
+			new([]complex128),
+			new([]string),
+			new([][]any),
+			new(mat.Dense),
+			new(mat.VecDense),
+			new(mat.CDense),
+			new([]mat.Dense),
+			new(Tensor[float32]),
+		} {
+			// default limits.
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				return
+			}
+			err = r.Read(ptr)
+			if err != nil {
+				continue
+			}
+			if arr, ok := ptr.(*Array); ok {
+				_, _ = arr.Native()
+			}
+		}
+	})
+}
+
+func TestReadHeader(t *testing.T) {
+	fnames, err := filepath.Glob("../testdata/*.npy")
+	if err != nil {
+		t.Fatalf("could not find files: %+v", err)
+	}
+	for _, fname := range fnames {
+		t.Run(filepath.Base(fname), func(t *testing.T) {
+			f, err := os.Open(fname)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			hdr, err := ReadHeader(f)
+			if err != nil {
+				t.Fatalf("could not read header: %+v", err)
+			}
+
+			pos, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				t.Fatalf("could not retrieve file position: %+v", err)
+			}
+			if got, want := hdr.DataOffset, pos; got != want {
+				t.Fatalf("invalid data offset: got=%d, want=%d", got, want)
+			}
+
+			dt, err := hdr.Dtype()
+			if err != nil {
+				t.Fatalf("could not parse dtype: %+v", err)
+			}
+
+			size, err := hdr.DataSize()
+			if dt.Kind() == 'O' {
+				if !errors.Is(err, ErrInvalidType) {
+					t.Fatalf("invalid error: got=%v, want=%v", err, ErrInvalidType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not compute data size: %+v", err)
+			}
+
+			esize, err := hdr.ItemSize()
+			if err != nil {
+				t.Fatalf("could not compute item size: %+v", err)
+			}
+			if got, want := size, int64(esize*hdr.Len()); got != want {
+				t.Fatalf("invalid data size: got=%d, want=%d", got, want)
+			}
+
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatalf("could not stat file: %+v", err)
+			}
+			if got, want := hdr.DataOffset+size, fi.Size(); got != want {
+				t.Fatalf("invalid file size: got=%d, want=%d", got, want)
+			}
+		})
+	}
+}
+
+func TestReadByteStrings(t *testing.T) {
+	want := [][]byte{[]byte("abc"), []byte("d"), {}, []byte("a\x00b")}
+
+	buf := new(bytes.Buffer)
+	err := Write(buf, want)
+	if err != nil {
+		t.Fatalf("could not write byte strings: %+v", err)
+	}
+
+	var got [][]byte
+	err = Read(buf, &got)
+	if err != nil {
+		t.Fatalf("could not read byte strings: %+v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid byte strings:\ngot= %q\nwant=%q", got, want)
+	}
+
+	buf.Reset()
+	err = Write(buf, []string{"abc"})
+	if err != nil {
+		t.Fatalf("could not write strings: %+v", err)
+	}
+	err = Read(buf, &got)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("invalid error reading unicode strings:\ngot= %v\nwant=%v", err, ErrTypeMismatch)
+	}
+}
+
+func TestReadStrings(t *testing.T) {
+	ucs4 := func(order binary.ByteOrder, n int, strs ...string) []byte {
+		o := make([]byte, 4*n*len(strs))
+		for i, str := range strs {
+			j := 4 * n * i
+			for _, r := range str {
+				order.PutUint32(o[j:], uint32(r))
+				j += 4
+			}
+		}
+		return o
+	}
+
+	want := []string{"a𝄞b", "", "世界"}
+	for _, tc := range []struct {
+		descr string
+		raw   []byte
+	}{
+		{"<U4", ucs4(binary.LittleEndian, 4, want...)},
+		{">U4", ucs4(binary.BigEndian, 4, want...)},
+		{"|S8", []byte("a\xf0\x9d\x84\x9eb\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x00" + "世界\x00\x00")},
+	} {
+		t.Run(tc.descr, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := writeHeader(buf, newTestHeader(tc.descr, false, []int{len(want)}))
+			if err != nil {
+				t.Fatalf("could not write header: %+v", err)
+			}
+			buf.Write(tc.raw)
+			raw := buf.Bytes()
+
+			var slice []string
+			err = Read(bytes.NewReader(raw), &slice)
+			if err != nil {
+				t.Fatalf("could not read slice: %+v", err)
+			}
+			if !reflect.DeepEqual(slice, want) {
+				t.Fatalf("invalid slice:\ngot= %q\nwant=%q", slice, want)
+			}
+
+			var array [3]string
+			err = Read(bytes.NewReader(raw), &array)
+			if err != nil {
+				t.Fatalf("could not read array: %+v", err)
+			}
+			if got := array[:]; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid array:\ngot= %q\nwant=%q", got, want)
+			}
+
+			var arr Array
+			err = Read(bytes.NewReader(raw), &arr)
+			if err != nil {
+				t.Fatalf("could not read Array: %+v", err)
+			}
+			if got := arr.Data(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid Array data:\ngot= %q\nwant=%q", got, want)
+			}
+
+			for i, v := range want {
+				buf := new(bytes.Buffer)
+				err := writeHeader(buf, newTestHeader(tc.descr, false, nil))
+				if err != nil {
+					t.Fatalf("could not write header: %+v", err)
+				}
+				n := len(tc.raw) / len(want)
+				buf.Write(tc.raw[i*n : (i+1)*n])
+
+				var got string
+				err = Read(buf, &got)
+				if err != nil {
+					t.Fatalf("could not read scalar %d: %+v", i, err)
+				}
+				if got != v {
+					t.Fatalf("invalid scalar %d:\ngot= %q\nwant=%q", i, got, v)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid-code-point", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := writeHeader(buf, newTestHeader("<U1", false, []int{1}))
+		if err != nil {
+			t.Fatalf("could not write header: %+v", err)
+		}
+		buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+
+		var got []string
+		err = Read(buf, &got)
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
+
+func TestReadWriteVoid(t *testing.T) {
+	want := [][4]byte{{1, 2, 3, 4}, {5, 6, 7, 8}, {0, 0, 0, 0}}
+
+	buf := new(bytes.Buffer)
+	err := Write(buf, want)
+	if err != nil {
+		t.Fatalf("could not write void data: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	hdr, err := ReadHeader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("could not read header: %+v", err)
+	}
+	if got, want := hdr.Descr.Type, "|V4"; got != want {
+		t.Fatalf("invalid descr:\ngot= %q\nwant=%q", got, want)
+	}
+
+	var fixed [][4]byte
+	err = Read(bytes.NewReader(raw), &fixed)
+	if err != nil {
+		t.Fatalf("could not read [][4]byte: %+v", err)
+	}
+	if !reflect.DeepEqual(fixed, want) {
+		t.Fatalf("invalid [][4]byte:\ngot= %v\nwant=%v", fixed, want)
+	}
+
+	var array [3][4]byte
+	err = Read(bytes.NewReader(raw), &array)
+	if err != nil {
+		t.Fatalf("could not read [3][4]byte: %+v", err)
+	}
+	if got := array[:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid [3][4]byte:\ngot= %v\nwant=%v", got, want)
+	}
+
+	slices := [][]byte{want[0][:], want[1][:], want[2][:]}
+	var sli [][]byte
+	err = Read(bytes.NewReader(raw), &sli)
+	if err != nil {
+		t.Fatalf("could not read [][]byte: %+v", err)
+	}
+	if !reflect.DeepEqual(sli, slices) {
+		t.Fatalf("invalid [][]byte:\ngot= %v\nwant=%v", sli, slices)
+	}
+
+	var arr Array
+	err = Read(bytes.NewReader(raw), &arr)
+	if err != nil {
+		t.Fatalf("could not read Array: %+v", err)
+	}
+	if got := arr.Data(); !reflect.DeepEqual(got, slices) {
+		t.Fatalf("invalid Array data:\ngot= %v\nwant=%v", got, slices)
+	}
+
+	out := new(bytes.Buffer)
+	err = Write(out, &arr)
+	if err != nil {
+		t.Fatalf("could not write Array: %+v", err)
+	}
+	if !bytes.Equal(out.Bytes(), raw) {
+		t.Fatalf("invalid round-trip:\ngot= %q\nwant=%q", out.Bytes(), raw)
+	}
+
+	var short [][2]byte
+	err = Read(bytes.NewReader(raw), &short)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("invalid error reading [][2]byte:\ngot= %v\nwant=%v", err, ErrTypeMismatch)
+	}
+
+	t.Run("structured", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		hdr := newHeader()
+		hdr.Descr.Type = "[('x', '<i2'), ('y', '|u1')]"
+		hdr.Descr.Shape = []int{2}
+		err := writeHeader(buf, hdr)
+		if err != nil {
+			t.Fatalf("could not write header: %+v", err)
+		}
+		buf.Write([]byte{1, 0, 2, 3, 0, 4})
+		raw := buf.Bytes()
+
+		want := [][]byte{{1, 0, 2}, {3, 0, 4}}
+		var got [][]byte
+		err = Read(bytes.NewReader(raw), &got)
+		if err != nil {
+			t.Fatalf("could not read records: %+v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid records:\ngot= %v\nwant=%v", got, want)
+		}
+
+		var arr Array
+		err = Read(bytes.NewReader(raw), &arr)
+		if err != nil {
+			t.Fatalf("could not read Array: %+v", err)
+		}
+		if got := arr.Data(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid Array data:\ngot= %v\nwant=%v", got, want)
+		}
+
+		out := new(bytes.Buffer)
+		err = Write(out, &arr)
+		if err != nil {
+			t.Fatalf("could not write Array: %+v", err)
+		}
+		if !bytes.Equal(out.Bytes(), raw) {
+			t.Fatalf("invalid round-trip:\ngot= %q\nwant=%q", out.Bytes(), raw)
+		}
+	})
+}
+
+func TestReaderPayload(t *testing.T) {
+	for _, fname := range []string{
+		"../testdata/data_float64_2x3_forder.npy",
+		"../testdata/data_int16_scalar_corder.npy",
+		"../testdata/ragged-array.npy",
+	} {
+		t.Run(filepath.Base(fname), func(t *testing.T) {
+			raw, err := os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+
+			// trailing data is only consumed for object arrays.
+			r, err := NewReader(bytes.NewReader(append(append([]byte(nil), raw...), "tail"...)))
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			got, err := io.ReadAll(r.Payload())
+			if err != nil {
+				t.Fatalf("could not read payload: %+v", err)
+			}
+
+			want := raw[r.Header.DataOffset:]
+			if r.Header.Descr.Type == "|O" {
+				want = append(want, "tail"...)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("invalid payload:\ngot= %x\nwant=%x", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads consecutive NumPy arrays from an input stream, as written
+// by an Encoder or by successive calls to numpy.save on the same file.
+//
+// A Decoder consumes exactly the header and the array data of each array,
+// so the stream can carry other data after the arrays.
+type Decoder struct {
+	r    io.Reader
+	opts []ReadOption
+	err  error // sticky stream error
+}
+
+// NewDecoder returns a new decoder that reads from r.
+// The provided options are applied to each decoded array.
+// As the size of a stream is not known in advance, decoders of untrusted
+// streams should bound the decoded arrays with WithMaxElements and
+// WithMaxBytes.
+func NewDecoder(r io.Reader, opts ...ReadOption) *Decoder {
+	return &Decoder{r: r, opts: opts}
+}
+
+// Decode reads the next array from the input stream and stores it in the
+// value pointed at by ptr, like Read.
+//
+// Decode returns io.EOF if the input stream holds no more arrays.
+// The array data not stored in ptr (e.g. when reading into a short slice,
+// or when the data type does not match) is skipped, so the next call to
+// Decode reads the next array.
+// Errors that leave the input stream in an unknown state (invalid headers,
+// truncated data, exceeded limits) are returned by all subsequent calls.
+func (dec *Decoder) Decode(ptr interface{}) error {
+	if dec.err != nil {
+		return dec.err
+	}
+
+	r, err := NewReader(dec.r, dec.opts...)
+	if err != nil {
+		dec.err = err
+		return dec.err
+	}
+
+	dt, err := r.dtype()
+	if err != nil {
+		dec.err = fmt.Errorf("npy: could not decode array data type: %w", err)
+		return dec.err
+	}
+
+	if dt.kind == 'O' {
+		// pickled data: read the pickle up to its STOP opcode.
+		src := &pickleStream{r: dec.r, lim: r.cfg.maxBytes}
+		err = scanPickle(src)
+		if err != nil {
+			if !errors.Is(err, ErrLimitExceeded) {
+				err = r.payloadError(int64(src.buf.Len()), err)
+			}
+			dec.err = err
+			return dec.err
+		}
+		r.r = bytes.NewReader(src.buf.Bytes())
+		return r.Read(ptr)
+	}
+
+	size, err := r.Header.DataSize()
+	if err != nil {
+		dec.err = err
+		return dec.err
+	}
+
+	lr := &io.LimitedReader{R: dec.r, N: size}
+	r.r = lr
+	err = r.Read(ptr)
+	if errors.Is(err, ErrLimitExceeded) {
+		dec.err = err
+		return dec.err
+	}
+
+	// skip the array data that was not consumed.
+	_, cerr := io.Copy(io.Discard, lr)
+	switch {
+	case cerr != nil:
+		dec.err = r.payloadError(size-lr.N, cerr)
+		return dec.err
+	case lr.N > 0:
+		dec.err = r.payloadError(size-lr.N, io.ErrUnexpectedEOF)
+		return dec.err
+	}
+	return err
+}
+
+// Encoder writes consecutive NumPy arrays to an output stream, like
+// successive calls to numpy.save on the same file.
+type Encoder struct {
+	w    io.Writer
+	opts []WriteOption
+}
+
+// NewEncoder returns a new encoder that writes to w.
+// The provided options are applied to each encoded array.
+func NewEncoder(w io.Writer, opts ...WriteOption) *Encoder {
+	return &Encoder{w: w, opts: opts}
+}
+
+// Encode writes val to the output stream, in the NumPy data format, like
+// Write.
+func (enc *Encoder) Encode(val interface{}) error {
+	return Write(enc.w, val, enc.opts...)
+}
+
+// pickleStream is a pickleSource reading from an input stream.
+// pickleStream keeps the consumed bytes, up to lim bytes (if lim > 0.)
+type pickleStream struct {
+	r   io.Reader
+	buf bytes.Buffer
+	lim int64
+}
+
+func (src *pickleStream) next(n int) ([]byte, error) {
+	if err := src.check(n); err != nil {
+		return nil, err
+	}
+	beg := src.buf.Len()
+	m, err := io.CopyN(&src.buf, src.r, int64(n))
+	if m != int64(n) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return src.buf.Bytes()[beg:], nil
+}
+
+func (src *pickleStream) line() error {
+	for {
+		p, err := src.next(1)
+		if err != nil {
+			return err
+		}
+		if p[0] == '\n' {
+			return nil
+		}
+	}
+}
+
+func (src *pickleStream) frame(n int) error {
+	// frames are checked against the consumed data when unpickling.
+	return nil
+}
+
+func (src *pickleStream) check(n int) error {
+	if src.lim > 0 && int64(src.buf.Len())+int64(n) > src.lim {
+		return &LimitError{Resource: "array data size", Size: -1, Limit: src.lim}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	ragged, err := os.ReadFile("../testdata/ragged-array.npy")
+	if err != nil {
+		t.Fatalf("could not read ragged array: %+v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	for _, v := range []any{
+		[]float64{1, 2, 3},
+		"hello",
+		[]int16{4, 5, 6},
+	} {
+		err := enc.Encode(v)
+		if err != nil {
+			t.Fatalf("could not encode %T: %+v", v, err)
+		}
+	}
+	beg := buf.Len()
+	buf.Write(ragged)
+	err = enc.Encode([]uint8{7, 8})
+	if err != nil {
+		t.Fatalf("could not encode []uint8: %+v", err)
+	}
+	raw := buf.Bytes()
+
+	t.Run("stream", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(raw))
+		for _, tc := range []struct {
+			ptr  any
+			want any
+			err  error
+		}{
+			{func() any { v := make([]float64, 2); return &v }(), &[]float64{1, 2}, nil},
+			{new(string), func() any { v := "hello"; return &v }(), nil},
+			{new([]float32), nil, ErrTypeMismatch},
+			{new([][]float64), &[][]float64{{1, 2, 3, 4}, {5, 6, 7}, {8, 9}}, nil},
+			{new([]uint8), &[]uint8{7, 8}, nil},
+			{new([]uint8), nil, io.EOF},
+			{new([]uint8), nil, io.EOF},
+		} {
+			err := dec.Decode(tc.ptr)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("invalid error for %T:\ngot= %v\nwant=%v", tc.ptr, err, tc.err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("could not decode %T: %+v", tc.ptr, err)
+			}
+			if !reflect.DeepEqual(tc.ptr, tc.want) {
+				t.Fatalf("invalid data:\ngot= %v\nwant=%v", tc.ptr, tc.want)
+			}
+		}
+	})
+
+	t.Run("trailing-data", func(t *testing.T) {
+		r := bytes.NewReader(append(append([]byte(nil), raw...), "tail"...))
+		dec := NewDecoder(r)
+		for i := 0; i < 5; i++ {
+			var arr Array
+			err := dec.Decode(&arr)
+			if err != nil {
+				t.Fatalf("could not decode array %d: %+v", i, err)
+			}
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("could not read trailing data: %+v", err)
+		}
+		if got, want := string(rest), "tail"; got != want {
+			t.Fatalf("invalid trailing data:\ngot= %q\nwant=%q", got, want)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, n := range []int{
+			10,                    // header.
+			len(raw) - 1,          // array data.
+			beg,                   // before the pickle.
+			beg + len(ragged) - 3, // pickled data.
+		} {
+			dec := NewDecoder(bytes.NewReader(raw[:n]))
+			var err error
+			for err == nil {
+				var arr Array
+				err = dec.Decode(&arr)
+			}
+			if n == beg {
+				// the stream ends at an array boundary.
+				if err != io.EOF {
+					t.Fatalf("invalid error for %d bytes:\ngot= %v\nwant=%v", n, err, io.EOF)
+				}
+				continue
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("invalid error for %d bytes:\ngot= %v\nwant=%v", n, err, io.ErrUnexpectedEOF)
+			}
+			if again := dec.Decode(new(Array)); again != err {
+				t.Fatalf("error is not sticky:\ngot= %v\nwant=%v", again, err)
+			}
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(ragged), WithMaxBytes(16))
+		err := dec.Decode(new([][]float64))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrLimitExceeded)
+		}
+	})
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Tensor is a typed N-dimensional array.
+//
+// The element at index (i0, i1, ...) is stored at
+// Data[i0*Strides[0] + i1*Strides[1] + ...].
+type Tensor[T Numeric] struct {
+	Data    []T
+	Shape   []int
+	Strides []int // strides, in number of elements.
+	Fortran bool  // whether Data is laid out in Fortran-order (column-major.)
+}
+
+// NewTensor creates a new C-ordered (row-major) tensor with the provided
+// shape.
+// If data is nil, a new zero-filled slice is allocated.
+// Otherwise, the returned tensor shares the storage of the data slice.
+func NewTensor[T Numeric](shape []int, data []T) (*Tensor[T], error) {
+	for i, dim := range shape {
+		if dim < 0 {
+			return nil, fmt.Errorf("npy: invalid shape[%d]=%d: %w", i, dim, ErrShapeMismatch)
+		}
+	}
+
+	n := numElems(shape)
+	switch {
+	case data == nil:
+		data = make([]T, n)
+	case len(data) != n:
+		return nil, fmt.Errorf("npy: shape %v (size=%d) does not match data length %d: %w", shape, n, len(data), ErrShapeMismatch)
+	}
+
+	shape = append([]int(nil), shape...)
+	return &Tensor[T]{
+		Data:    data,
+		Shape:   shape,
+		Strides: tensorStrides(shape, false),
+	}, nil
+}
+
+// TensorFromArray creates a new tensor from the provided array.
+// TensorFromArray returns an error if the array elements are not of type T.
+//
+// The returned tensor shares the storage of the array, unless the array
+// is a strided view, in which case the data is copied.
+func TensorFromArray[T Numeric](arr *Array) (*Tensor[T], error) {
+	if !arr.isCompact() {
+		arr = arr.Contiguous()
+	}
+
+	var data []T
+	switch v := arr.data.(type) {
+	case []T:
+		data = v
+	case T:
+		data = []T{v}
+	default:
+		return nil, fmt.Errorf("npy: can not create Tensor[%T] from array of %T: %w", *new(T), arr.data, ErrTypeMismatch)
+	}
+
+	strides := make([]int, len(arr.strides))
+	for i, v := range arr.strides {
+		strides[i] = v / arr.descr.esize
+	}
+
+	return &Tensor[T]{
+		Data:    data,
+		Shape:   append([]int(nil), arr.shape...),
+		Strides: strides,
+		Fortran: arr.fortran,
+	}, nil
+}
+
+// TensorFromMatrix creates a new 2-dimensional tensor with the content
+// of the provided matrix.
+func TensorFromMatrix(m mat.Matrix) *Tensor[float64] {
+	var (
+		nrows, ncols = m.Dims()
+		data         = make([]float64, nrows*ncols)
+	)
+	for i := 0; i < nrows; i++ {
+		for j := 0; j < ncols; j++ {
+			data[i*ncols+j] = m.At(i, j)
+		}
+	}
+	return &Tensor[float64]{
+		Data:    data,
+		Shape:   []int{nrows, ncols},
+		Strides: []int{ncols, 1},
+	}
+}
+
+func tensorStrides(shape []int, fortran bool) []int {
+	var (
+		strides = make([]int, len(shape))
+		stride  = 1
+	)
+	for k := range shape {
+		i := len(shape) - 1 - k
+		if fortran {
+			i = k
+		}
+		strides[i] = stride
+		stride *= shape[i]
+	}
+	return strides
+}
+
+// strides returns the tensor strides, inferring them from the shape and
+// the memory layout when not explicitly provided.
+func (t *Tensor[T]) strides() []int {
+	if t.Strides != nil || t.Shape == nil {
+		return t.Strides
+	}
+	return tensorStrides(t.Shape, t.Fortran)
+}
+
+// Len returns the number of elements of the tensor.
+func (t *Tensor[T]) Len() int {
+	return numElems(t.Shape)
+}
+
+// At returns the element at the provided multi-dimensional index.
+// At panics if the number of indices does not match the number of
+// dimensions of the tensor, or if an index is out of range.
+func (t *Tensor[T]) At(idx ...int) T {
+	return t.Data[t.index(idx)]
+}
+
+// Set sets the element at the provided multi-dimensional index to v.
+// Set panics if the number of indices does not match the number of
+// dimensions of the tensor, or if an index is out of range.
+func (t *Tensor[T]) Set(v T, idx ...int) {
+	t.Data[t.index(idx)] = v
+}
+
+func (t *Tensor[T]) index(idx []int) int {
+	if len(idx) != len(t.Shape) {
+		panic(fmt.Errorf("npy: invalid number of indices (got=%d, want=%d)", len(idx), len(t.Shape)))
+	}
+	var (
+		strides = t.strides()
+		i       = 0
+	)
+	for k, v := range idx {
+		if v < 0 || v >= t.Shape[k] {
+			panic(fmt.Errorf("npy: index %d out of range for axis %d with size %d", v, k, t.Shape[k]))
+		}
+		i += v * strides[k]
+	}
+	return i
+}
+
+// Each calls f for each element of the tensor, in C-order (row-major),
+// with the element's multi-dimensional index and value.
+// The index slice is reused between calls and must not be retained.
+func (t *Tensor[T]) Each(f func(idx []int, v T)) {
+	n := t.Len()
+	if n == 0 {
+		return
+	}
+	var (
+		ndim    = len(t.Shape)
+		strides = t.strides()
+		idx     = make([]int, ndim)
+		j       = 0
+	)
+	for i := 0; i < n; i++ {
+		f(idx, t.Data[j])
+		for k := ndim - 1; k >= 0; k-- {
+			idx[k]++
+			j += strides[k]
+			if idx[k] < t.Shape[k] {
+				break
+			}
+			j -= idx[k] * strides[k]
+			idx[k] = 0
+		}
+	}
+}
+
+// Row returns a copy of the i-th row of the tensor (i.e. the i-th
+// sub-tensor along the first axis), flattened in C-order.
+// Row panics if the tensor has no dimension or if i is out of range.
+func (t *Tensor[T]) Row(i int) []T {
+	if len(t.Shape) == 0 {
+		panic(fmt.Errorf("npy: can not extract row from 0-dimensional tensor"))
+	}
+	if i < 0 || i >= t.Shape[0] {
+		panic(fmt.Errorf("npy: index %d out of range for axis 0 with size %d", i, t.Shape[0]))
+	}
+
+	var (
+		strides = t.strides()
+		sub     = Tensor[T]{
+			Data:    t.Data[i*strides[0]:],
+			Shape:   t.Shape[1:],
+			Strides: strides[1:],
+		}
+		row = make([]T, 0, sub.Len())
+	)
+	sub.Each(func(_ []int, v T) {
+		row = append(row, v)
+	})
+	return row
+}
+
+// isCompact returns whether the tensor data exactly holds its elements,
+// laid out in C- or Fortran-order (depending on the Fortran flag.)
+func (t *Tensor[T]) isCompact() bool {
+	if len(t.Data) != t.Len() {
+		return false
+	}
+	var (
+		got  = t.strides()
+		want = tensorStrides(t.Shape, t.Fortran)
+	)
+	for i := range want {
+		if got[i] != want[i] && t.Shape[i] != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// contiguous returns the tensor data, laid out in C-order.
+func (t *Tensor[T]) contiguous() []T {
+	data := make([]T, 0, t.Len())
+	t.Each(func(_ []int, v T) {
+		data = append(data, v)
+	})
+	return data
+}
+
+// Array returns a new array with the content of the tensor.
+// The returned array shares the storage of the tensor.
+func (t *Tensor[T]) Array() (*Array, error) {
+	rv := reflect.ValueOf(t.Data)
+	str, err := dtypeFrom(rv, rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	descr, err := ParseDescr(str)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		strides = t.strides()
+		bytes   = make([]int, len(strides))
+	)
+	for i, v := range strides {
+		bytes[i] = v * descr.esize
+	}
+
+	return NewArray(
+		descr, append([]int(nil), t.Shape...), t.Data,
+		WithStrides(bytes...),
+		WithFortranOrder(t.Fortran),
+	)
+}
+
+// Dense returns a new matrix with the content of the 2-dimensional tensor.
+// Dense returns an error if the tensor is not 2-dimensional, or if its
+// elements are not real numbers.
+func (t *Tensor[T]) Dense() (*mat.Dense, error) {
+	if len(t.Shape) != 2 {
+		return nil, fmt.Errorf("npy: can not create a matrix from a %d-dimensional tensor: %w", len(t.Shape), ErrShapeMismatch)
+	}
+
+	var (
+		nrows = t.Shape[0]
+		ncols = t.Shape[1]
+		data  = make([]float64, nrows*ncols)
+		err   error
+	)
+	t.Each(func(idx []int, v T) {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			data[idx[0]*ncols+idx[1]] = float64(rv.Int())
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			data[idx[0]*ncols+idx[1]] = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			data[idx[0]*ncols+idx[1]] = rv.Float()
+		default:
+			err = fmt.Errorf("npy: can not convert %T to float64: %w", v, ErrInvalidType)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if nrows == 0 || ncols == 0 {
+		return &mat.Dense{}, nil
+	}
+	return mat.NewDense(nrows, ncols, data), nil
+}
+
+// tensorReader is implemented by the Tensor types, to be read in by
+// Reader.Read.
+type tensorReader interface {
+	readTensor(r *Reader) error
+}
+
+// tensorWriter is implemented by the Tensor types, to be written out by
+// Write.
+type tensorWriter interface {
+	writeTensor(w io.Writer) error
+}
+
+var (
+	_ tensorReader = (*Tensor[float64])(nil)
+	_ tensorWriter = (*Tensor[float64])(nil)
+)
+
+func (t *Tensor[T]) readTensor(r *Reader) error {
+	var data []T
+	err := r.readValue(&data)
+	if err != nil {
+		return err
+	}
+
+	t.Data = data
+	t.Shape = r.Header.Descr.Shape
+	t.Fortran = r.Header.Descr.Fortran
+	t.Strides = tensorStrides(t.Shape, t.Fortran)
+	return nil
+}
+
+func (t *Tensor[T]) writeTensor(w io.Writer) error {
+	if t.isCompact() {
+		return WriteShaped(w, t.Data, t.Shape, t.Fortran)
+	}
+	return WriteShaped(w, t.contiguous(), t.Shape, false)
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 The npyio Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package npy
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTensor(t *testing.T) {
+	ten, err := NewTensor([]int{2, 3, 4}, []int16{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23,
+	})
+	if err != nil {
+		t.Fatalf("could not create tensor: %+v", err)
+	}
+
+	if got, want := ten.Len(), 24; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+	if got, want := ten.Strides, []int{12, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := ten.At(1, 2, 3), int16(23); got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+
+	ten.Set(-1, 0, 1, 2)
+	if got, want := ten.Data[6], int16(-1); got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+	ten.Set(6, 0, 1, 2)
+
+	if got, want := ten.Row(1), []int16{12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid row:\ngot= %v\nwant=%v", got, want)
+	}
+
+	var (
+		idxs [][]int
+		vals []int16
+	)
+	ten.Each(func(idx []int, v int16) {
+		if v%10 == 0 {
+			idxs = append(idxs, append([]int(nil), idx...))
+			vals = append(vals, v)
+		}
+	})
+	if got, want := idxs, [][]int{{0, 0, 0}, {0, 2, 2}, {1, 2, 0}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid indices:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := vals, []int16{0, 10, 20}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid values:\ngot= %v\nwant=%v", got, want)
+	}
+
+	// a transposed view.
+	tr := &Tensor[int16]{
+		Data:    ten.Data,
+		Shape:   []int{4, 3, 2},
+		Strides: []int{1, 4, 12},
+	}
+	if got, want := tr.At(3, 1, 0), int16(7); got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+	if got, want := tr.Row(3), []int16{3, 15, 7, 19, 11, 23}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid row:\ngot= %v\nwant=%v", got, want)
+	}
+
+	for _, idx := range [][]int{
+		{},
+		{0, 0},
+		{2, 0, 0},
+		{0, -1, 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected a panic for index %v", idx)
+				}
+			}()
+			_ = ten.At(idx...)
+		}()
+	}
+
+	_, err = NewTensor([]int{2, 3}, []float64{1, 2})
+	if !errors.Is(err, ErrShapeMismatch) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrShapeMismatch)
+	}
+
+	zeros, err := NewTensor[complex64]([]int{2, 2}, nil)
+	if err != nil {
+		t.Fatalf("could not create tensor: %+v", err)
+	}
+	if got, want := zeros.Data, make([]complex64, 4); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+}
+
+func TestTensorRW(t *testing.T) {
+	for _, fname := range []string{
+		"../testdata/data_float64_2x3_corder.npy",
+		"../testdata/data_float64_2x3_forder.npy",
+		"../testdata/data_float64_2x3x4_corder.npy",
+		"../testdata/data_float64_scalar_corder.npy",
+	} {
+		t.Run(fname, func(t *testing.T) {
+			f, err := os.Open(fname)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			var want Tensor[float64]
+			err = Read(f, &want)
+			if err != nil {
+				t.Fatalf("could not read tensor: %+v", err)
+			}
+
+			_, err = f.Seek(0, 0)
+			if err != nil {
+				t.Fatalf("could not rewind file: %+v", err)
+			}
+			var arr Array
+			err = Read(f, &arr)
+			if err != nil {
+				t.Fatalf("could not read array: %+v", err)
+			}
+
+			if got, want := want.Shape, arr.Shape(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+			if got, want := want.Fortran, arr.Fortran(); got != want {
+				t.Fatalf("invalid fortran: got=%v, want=%v", got, want)
+			}
+			want.Each(func(idx []int, v float64) {
+				if got := arr.At(idx...); got != v {
+					t.Fatalf("invalid value at %v: got=%v, want=%v", idx, got, v)
+				}
+			})
+
+			buf := new(bytes.Buffer)
+			err = Write(buf, &want)
+			if err != nil {
+				t.Fatalf("could not write tensor: %+v", err)
+			}
+
+			var got Tensor[float64]
+			err = Read(buf, &got)
+			if err != nil {
+				t.Fatalf("could not read back tensor: %+v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid r/w round-trip:\ngot= %+v\nwant=%+v", got, want)
+			}
+		})
+	}
+
+	t.Run("view", func(t *testing.T) {
+		ten := &Tensor[int32]{
+			Data:    []int32{0, 1, 2, 3, 4, 5},
+			Shape:   []int{2, 2},
+			Strides: []int{3, 1},
+		}
+		buf := new(bytes.Buffer)
+		err := Write(buf, ten)
+		if err != nil {
+			t.Fatalf("could not write tensor: %+v", err)
+		}
+
+		data, shape, err := ReadAs[int32](buf)
+		if err != nil {
+			t.Fatalf("could not read back data: %+v", err)
+		}
+		if got, want := shape, []int{2, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+		if got, want := data, []int32{0, 1, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+		}
+	})
+
+	t.Run("type-mismatch", func(t *testing.T) {
+		f, err := os.Open("../testdata/data_float64_2x3_corder.npy")
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+
+		var ten Tensor[float32]
+		err = Read(f, &ten)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, ErrTypeMismatch)
+		}
+	})
+}
+
+func TestTensorConvert(t *testing.T) {
+	arr := loadArray(t, "../testdata/data_float32_2x3_forder.npy")
+
+	ten, err := TensorFromArray[float32](arr)
+	if err != nil {
+		t.Fatalf("could not create tensor from array: %+v", err)
+	}
+	if got, want := ten.Strides, []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid strides:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := ten.At(0, 1), arr.At(0, 1); got != want {
+		t.Fatalf("invalid value: got=%v, want=%v", got, want)
+	}
+
+	back, err := ten.Array()
+	if err != nil {
+		t.Fatalf("could not create array from tensor: %+v", err)
+	}
+	if !reflect.DeepEqual(back, arr) {
+		t.Fatalf("invalid round-trip:\ngot= %v\nwant=%v", back, arr)
+	}
+
+	_, err = TensorFromArray[float64](arr)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrTypeMismatch)
+	}
+
+	view, err := arr.Slice(1, 1, 3)
+	if err != nil {
+		t.Fatalf("could not slice array: %+v", err)
+	}
+	sub, err := TensorFromArray[float32](view)
+	if err != nil {
+		t.Fatalf("could not create tensor from view: %+v", err)
+	}
+	if got, want := sub.Data, []float32{2, 4, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+
+	m, err := ten.Dense()
+	if err != nil {
+		t.Fatalf("could not create matrix: %+v", err)
+	}
+	want := mat.NewDense(2, 3, []float64{0, 2, 4, 1, 3, 5})
+	if !mat.Equal(m, want) {
+		t.Fatalf("invalid matrix:\ngot= %v\nwant=%v", mat.Formatted(m), mat.Formatted(want))
+	}
+
+	mt := TensorFromMatrix(want.T())
+	if got, want := mt.Shape, []int{3, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+	if got, want := mt.Data, []float64{0, 1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+	}
+
+	cplx, err := NewTensor[complex128]([]int{1, 1}, nil)
+	if err != nil {
+		t.Fatalf("could not create tensor: %+v", err)
+	}
+	_, err = cplx.Dense()
+	if !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrInvalidType)
+	}
+
+	_, err = (&Tensor[float32]{Data: []float32{1}}).Dense()
+	if !errors.Is(err, ErrShapeMismatch) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, ErrShapeMismatch)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -19,20 +20,137 @@ import (
 )
 
 var (
-	rtDense = reflect.TypeOf((*mat.Dense)(nil)).Elem()
+	rtDense    = reflect.TypeOf((*mat.Dense)(nil)).Elem()
+	rtCDense   = reflect.TypeOf((*mat.CDense)(nil)).Elem()
+	rtVecDense = reflect.TypeOf((*mat.VecDense)(nil)).Elem()
 )
 
+// WriteOption configures how NumPy data files are written.
+type WriteOption func(*writeConfig)
+
+type writeConfig struct {
+	workers  int  // number of goroutines used by WriteAt (0: GOMAXPROCS).
+	strKind  byte // data type kind of strings ('S' or 'U', 0: default.)
+	strWidth int  // width of strings (0: width of the longest string.)
+	truncate bool // whether to truncate strings longer than strWidth.
+}
+
+func newWriteConfig(opts []WriteOption) writeConfig {
+	var cfg writeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithStringKind sets the data type kind of written strings:
+// 'S' for byte strings or 'U' for unicode strings.
+// By default, strings are written out as unicode strings and
+// [][]byte values as byte strings.
+func WithStringKind(kind byte) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.strKind = kind
+	}
+}
+
+// WithStringWidth sets the width of written strings, in bytes for byte
+// strings and in characters (runes) for unicode strings.
+// By default, the width of the longest string is used.
+//
+// Writing a string longer than the width is an error, unless
+// WithStringTruncate is used.
+func WithStringWidth(n int) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.strWidth = n
+	}
+}
+
+// WithStringTruncate sets whether strings longer than the width set with
+// WithStringWidth are truncated, instead of failing the write.
+func WithStringTruncate(v bool) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.truncate = v
+	}
+}
+
 // Write writes 'val' into 'w' in the NumPy data format.
 //
 //   - if val is a scalar, it must be of a supported type (bools, (u)ints, floats and complexes)
 //   - if val is a slice or array, it must be a slice/array of a supported type.
 //     the shape (len,) will be written out.
 //   - if val is a mat.Dense, the correct shape will be transmitted. (ie: (nrows, ncols))
+//   - if val is a mat.Vector (e.g. a *mat.VecDense), the shape (len,) will be written out.
+//   - if val is any other mat.Matrix (e.g. a *mat.SymDense, *mat.TriDense or *mat.BandDense),
+//     its elements will be written out as a dense (nrows, ncols) float64 array.
+//   - if val is a []mat.Dense or a []*mat.Dense, the shape (len, nrows, ncols)
+//     will be written out. All the matrices must have the same dimensions.
+//   - if val is a mat.CMatrix (e.g. a *mat.CDense), its elements will be written
+//     out as a dense (nrows, ncols) complex128 array.
+//   - if val is an Array, its data type, byte order, shape and memory layout
+//     (C- or Fortran-order) will be written out.
+//     Strided views are written out as C-contiguous arrays.
+//     Object ('O') arrays are written out as python pickles, with the
+//     metadata of their data type.
+//   - if val is a *Tensor[T], its shape and memory layout will be written out.
+//   - if val is a string, a []string or a [N]string, it will be written out
+//     as a unicode ('U') array, unless WithStringKind is used.
+//   - if val is a [][]byte, it will be written out as a byte-string ('S') array,
+//     unless WithStringKind is used.
+//   - if val is a [][n]byte, it will be written out as an opaque ('|Vn') array.
+//   - if val is a *big.Float or a []*big.Float (resp. a BigComplex or a []BigComplex),
+//     it will be written out as an extended precision longdouble ('<f16')
+//     (resp. clongdouble ('<c32')) array, in the x87 80-bit format.
+//     nil *big.Float values are written out as NaNs.
 //
-// The data-array will always be written out in C-order (row-major).
-func Write(w io.Writer, val interface{}) error {
-	hdr := newHeader()
+// Except for Arrays, the data-array will always be written out in C-order (row-major).
+func Write(w io.Writer, val interface{}, opts ...WriteOption) error {
+	switch arr := val.(type) {
+	case *Array:
+		return writeArray(w, *arr)
+	case Array:
+		return writeArray(w, arr)
+	case tensorWriter:
+		return arr.writeTensor(w)
+	case []mat.Dense:
+		ms := make([]mat.Matrix, len(arr))
+		for i := range arr {
+			ms[i] = &arr[i]
+		}
+		return writeMatrices(w, ms)
+	case []*mat.Dense:
+		ms := make([]mat.Matrix, len(arr))
+		for i, m := range arr {
+			if m == nil {
+				return fmt.Errorf("npy: nil matrix at index %d", i)
+			}
+			ms[i] = m
+		}
+		return writeMatrices(w, ms)
+	case mat.Vector:
+		return writeVector(w, arr)
+	case mat.Matrix:
+		return writeMatrix(w, arr)
+	case mat.CMatrix:
+		return writeCMatrix(w, arr)
+	case *big.Float:
+		return writeExtended(w, []*big.Float{arr}, nil)
+	case []*big.Float:
+		return writeExtended(w, arr, []int{len(arr)})
+	case BigComplex:
+		return writeExtended(w, []BigComplex{arr}, nil)
+	case []BigComplex:
+		return writeExtended(w, arr, []int{len(arr)})
+	}
+
 	rv := reflect.Indirect(reflect.ValueOf(val))
+	if strs, shape, kind, ok := stringsFrom(rv); ok {
+		return writeStrings(w, strs, shape, kind, newWriteConfig(opts))
+	}
+	if rt := rv.Type(); rt.Kind() == reflect.Slice && isVoid(rt.Elem()) {
+		return writeVoid(w, rv)
+	}
+
+	hdr := newHeader()
 	dt, err := dtypeFrom(rv, rv.Type())
 	if err != nil {
 		return err
@@ -49,7 +167,7 @@ func Write(w io.Writer, val interface{}) error {
 		return err
 	}
 
-	err = writeHeader(w, hdr, rdt)
+	err = writeHeader(w, hdr)
 	if err != nil {
 		return err
 	}
@@ -57,70 +175,464 @@ func Write(w io.Writer, val interface{}) error {
 	return writeData(w, rv, rdt)
 }
 
-func writeHeader(w io.Writer, hdr Header, dt dType) error {
-	err := binary.Write(w, dt.order, Magic[:])
+// WriteRaw writes a NumPy data file into w, made of the provided header
+// and of the array data read from payload, without encoding it.
+// The array data must be laid out as described by the header (data type,
+// memory layout and shape), e.g. as returned by Reader.Payload.
+// A header with a zero major version number is written out with the
+// version numbers npy currently supports.
+//
+// WriteRaw returns a *PayloadError if payload does not hold exactly
+// Header.DataSize bytes.
+// The array data is streamed from payload into w: when the size of payload
+// can not be determined beforehand (it is not a bytes.Reader, an io.Seeker,
+// an io.SectionReader, ...), a short or long payload is only detected once
+// the header and (part of) the array data have been written out.
+// For object ('O') arrays, whose pickled data size is not known in
+// advance, all of payload is written out.
+func WriteRaw(w io.Writer, hdr Header, payload io.Reader) error {
+	dt, err := hdr.Dtype()
+	if err != nil {
+		return fmt.Errorf("npy: invalid header data type: %w", err)
+	}
+	if hdr.Major == 0 {
+		v := newHeader()
+		hdr.Major, hdr.Minor = v.Major, v.Minor
+	}
+
+	raw, err := encodeHeader(hdr)
 	if err != nil {
 		return err
 	}
-	err = binary.Write(w, dt.order, hdr.Major)
+
+	if dt.kind == 'O' {
+		err = writeRawHeader(w, hdr, raw)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, payload)
+		return err
+	}
+
+	size, err := hdr.DataSize()
 	if err != nil {
 		return err
 	}
-	err = binary.Write(w, dt.order, hdr.Minor)
+
+	// payloadError describes a payload holding n bytes of array data
+	// (or more, if n > size.)
+	payloadError := func(n int64) error {
+		perr := &PayloadError{
+			Element: -1,
+			Err:     io.ErrUnexpectedEOF,
+		}
+		if n > size {
+			n = size
+			perr.Err = fmt.Errorf("array data exceeds header data size (%d bytes)", size)
+		}
+		perr.ByteOffset = int64(len(raw)) + n
+		if dt.esize > 0 {
+			perr.Element = int(n / int64(dt.esize))
+		}
+		return perr
+	}
+
+	if n, ok := payloadSize(payload); ok && n != size {
+		return payloadError(n)
+	}
+
+	err = writeRawHeader(w, hdr, raw)
 	if err != nil {
 		return err
 	}
 
-	buf := new(bytes.Buffer)
-	fmt.Fprintf(buf, "{'descr': '%s', 'fortran_order': False, 'shape': %s, }",
-		hdr.Descr.Type,
-		shapeString(hdr.Descr.Shape),
+	n, err := io.CopyN(w, payload, size)
+	switch {
+	case err == io.EOF:
+		return payloadError(n)
+	case err != nil:
+		return fmt.Errorf("npy: could not write array data: %w", err)
+	}
+
+	// make sure payload does not hold more data than announced.
+	var probe [1]byte
+	k, err := io.ReadFull(payload, probe[:])
+	switch {
+	case k > 0:
+		return payloadError(size + int64(k))
+	case err != nil && err != io.EOF:
+		return fmt.Errorf("npy: could not read array data: %w", err)
+	}
+	return nil
+}
+
+// payloadSize returns the exact number of bytes held by r, if known.
+func payloadSize(r io.Reader) (int64, bool) {
+	if lr, ok := r.(*io.LimitedReader); ok {
+		// remaining only bounds the size of a limited reader whose
+		// underlying reader size is not known.
+		if _, ok := remaining(lr.R); !ok {
+			return 0, false
+		}
+	}
+	return remaining(r)
+}
+
+// writeArray writes the provided array into w.
+func writeArray(w io.Writer, arr Array) error {
+	arr, fortran := arrayLayout(arr)
+
+	var (
+		raw []byte
+		err error
 	)
-	var hdrSize int
-	switch hdr.Major {
-	case 1:
-		hdrSize = 4 + len(Magic)
-	case 2:
-		hdrSize = 6 + len(Magic)
+	switch arr.descr.kind {
+	case 'O':
+		raw, err = pickleDumps(&arr)
+	default:
+		raw, err = arr.descr.marshal(arr.data)
+	}
+	if err != nil {
+		return err
+	}
+
+	hdr := newHeader()
+	hdr.Descr.Type = arr.descr.Descr()
+	hdr.Descr.Fortran = fortran
+	hdr.Descr.Shape = arr.shape
+
+	err = writeHeader(w, hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(raw)
+	return err
+}
+
+// writeVector writes the provided vector into w, as a 1-dimensional array.
+func writeVector(w io.Writer, v mat.Vector) error {
+	data := make([]float64, v.Len())
+	for i := range data {
+		data[i] = v.AtVec(i)
+	}
+	return WriteShaped(w, data, []int{len(data)}, false)
+}
+
+// writeMatrix writes the provided matrix into w, as a 2-dimensional
+// C-ordered array.
+func writeMatrix(w io.Writer, m mat.Matrix) error {
+	var (
+		nrows, ncols = m.Dims()
+		data         = make([]float64, nrows*ncols)
+	)
+	for i := 0; i < nrows; i++ {
+		for j := 0; j < ncols; j++ {
+			data[i*ncols+j] = m.At(i, j)
+		}
+	}
+	return WriteShaped(w, data, []int{nrows, ncols}, false)
+}
+
+// writeMatrices writes the provided matrices into w, as a 3-dimensional
+// (batch, rows, cols) C-ordered array.
+func writeMatrices(w io.Writer, ms []mat.Matrix) error {
+	var nrows, ncols int
+	if len(ms) > 0 {
+		nrows, ncols = ms[0].Dims()
+	}
+	data := make([]float64, 0, len(ms)*nrows*ncols)
+	for k, m := range ms {
+		r, c := m.Dims()
+		if r != nrows || c != ncols {
+			return fmt.Errorf(
+				"npy: matrix %d has dimensions (%d, %d), want (%d, %d): %w",
+				k, r, c, nrows, ncols, ErrShapeMismatch,
+			)
+		}
+		for i := 0; i < nrows; i++ {
+			for j := 0; j < ncols; j++ {
+				data = append(data, m.At(i, j))
+			}
+		}
+	}
+	return WriteShaped(w, data, []int{len(ms), nrows, ncols}, false)
+}
+
+// writeCMatrix writes the provided complex matrix into w, as a
+// 2-dimensional C-ordered array.
+func writeCMatrix(w io.Writer, m mat.CMatrix) error {
+	var (
+		nrows, ncols = m.Dims()
+		data         = make([]complex128, nrows*ncols)
+	)
+	for i := 0; i < nrows; i++ {
+		for j := 0; j < ncols; j++ {
+			data[i*ncols+j] = m.At(i, j)
+		}
+	}
+	return WriteShaped(w, data, []int{nrows, ncols}, false)
+}
+
+// stringsFrom returns the strings held by the provided value, together
+// with their shape and default data type kind, if the value is a string,
+// a []string, a [N]string or a [][]byte.
+func stringsFrom(rv reflect.Value) (strs []string, shape []int, kind byte, ok bool) {
+	switch v := rv.Interface().(type) {
+	case string:
+		return []string{v}, nil, 'U', true
+	case []string:
+		return v, []int{len(v)}, 'U', true
+	case [][]byte:
+		strs = make([]string, len(v))
+		for i, b := range v {
+			strs[i] = string(b)
+		}
+		return strs, []int{len(v)}, 'S', true
+	}
+
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.String {
+		strs = make([]string, rv.Len())
+		for i := range strs {
+			strs[i] = rv.Index(i).String()
+		}
+		return strs, []int{len(strs)}, 'U', true
+	}
+
+	return nil, nil, 0, false
+}
+
+// writeStrings writes the provided strings into w, as a byte-string ('S')
+// or unicode ('U') array with the provided shape.
+func writeStrings(w io.Writer, strs []string, shape []int, kind byte, cfg writeConfig) error {
+	if cfg.strKind != 0 {
+		kind = cfg.strKind
+	}
+
+	var strlen func(str string) int
+	switch kind {
+	case 'S':
+		strlen = func(str string) int { return len(str) }
+	case 'U':
+		strlen = utf8.RuneCountInString
 	default:
-		return fmt.Errorf("npy: imvalid major version number (%d)", hdr.Major)
+		return fmt.Errorf("npy: invalid string kind %q: %w", kind, ErrInvalidType)
+	}
+
+	width := cfg.strWidth
+	if width <= 0 {
+		// like NumPy, never create zero-sized string data types.
+		width = 1
+		for _, str := range strs {
+			if n := strlen(str); n > width {
+				width = n
+			}
+		}
+	}
+
+	copied := false
+	for i, str := range strs {
+		if strlen(str) <= width {
+			continue
+		}
+		if !cfg.truncate {
+			return fmt.Errorf("npy: string %d (%q) is longer than %d characters", i, str, width)
+		}
+		if !copied {
+			// do not modify the user provided slice.
+			strs = append([]string(nil), strs...)
+			copied = true
+		}
+		strs[i] = truncateString(str, width, kind)
 	}
 
-	padding := (hdrSize + buf.Len() + 1) % 16
-	_, err = buf.Write(bytes.Repeat([]byte{'\x20'}, padding))
+	order := "<"
+	if kind == 'S' {
+		order = "|"
+	}
+	descr, err := ParseDescr(fmt.Sprintf("%s%c%d", order, kind, width))
+	if err != nil {
+		return err
+	}
+	raw, err := descr.marshal(strs)
 	if err != nil {
 		return err
 	}
-	_, err = buf.Write([]byte{'\n'})
+
+	hdr := newHeader()
+	hdr.Descr.Type = descr.Descr()
+	hdr.Descr.Shape = shape
+
+	err = writeHeader(w, hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(raw)
+	return err
+}
+
+// isVoid returns whether rt is a [n]byte array type, written out as an
+// opaque ('V') data type.
+func isVoid(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Array && rt.Elem().Kind() == reflect.Uint8
+}
+
+// writeVoid writes the provided [][n]byte slice into w, as an opaque
+// ('V') array of n-bytes elements.
+func writeVoid(w io.Writer, rv reflect.Value) error {
+	var (
+		n   = rv.Type().Elem().Len()
+		raw = make([]byte, rv.Len()*n)
+	)
+	for i := 0; i < rv.Len(); i++ {
+		reflect.Copy(reflect.ValueOf(raw[i*n:(i+1)*n]), rv.Index(i))
+	}
+
+	hdr := newHeader()
+	hdr.Descr.Type = fmt.Sprintf("|V%d", n)
+	hdr.Descr.Shape = []int{rv.Len()}
+
+	err := writeHeader(w, hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(raw)
+	return err
+}
+
+// truncateString truncates the provided string to n bytes, for byte
+// strings, or to n runes, for unicode strings.
+func truncateString(str string, n int, kind byte) string {
+	if kind == 'S' {
+		return str[:n]
+	}
+	for i := range str {
+		if n == 0 {
+			return str[:i]
+		}
+		n--
+	}
+	return str
+}
+
+// arrayLayout returns the provided array with a contiguous memory layout,
+// ready to be written out, and whether it should be written out in
+// Fortran-order.
+func arrayLayout(arr Array) (Array, bool) {
+	switch {
+	case arr.descr.kind == 'O':
+		// object arrays are pickled in C-order.
+		return arr, false
+	case arr.isCompact() && arr.fortran && arr.isFContiguous():
+		return arr, true
+	case arr.isCompact() && arr.isCContiguous():
+		return arr, false
+	case arr.isCompact() && arr.isFContiguous():
+		return arr, true
+	default:
+		return *arr.Contiguous(), false
+	}
+}
+
+// writeHeader writes the provided header into w.
+// The header is fully validated before any byte is written out.
+func writeHeader(w io.Writer, hdr Header) error {
+	raw, err := encodeHeader(hdr)
 	if err != nil {
 		return err
 	}
+	return writeRawHeader(w, hdr, raw)
+}
+
+// headerAlign is the alignment, in bytes, of the array data of the NumPy
+// data files written out, like numpy's.
+const headerAlign = 64
 
-	buflen := int64(buf.Len())
+// encodeHeader returns the on-disk representation of the provided header.
+func encodeHeader(hdr Header) ([]byte, error) {
+	var hdrSize int
 	switch hdr.Major {
 	case 1:
-		err = binary.Write(w, dt.order, uint16(buflen))
+		hdrSize = 4 + len(Magic)
 	case 2:
-		err = binary.Write(w, dt.order, uint32(buflen))
+		hdrSize = 6 + len(Magic)
 	default:
-		return fmt.Errorf("npy: invalid major version number (%d)", hdr.Major)
+		return nil, fmt.Errorf("npy: invalid major version number (%d)", hdr.Major)
 	}
 
-	if err != nil {
-		return err
+	descr := hdr.Descr.Type
+	if !strings.HasPrefix(descr, "[") {
+		descr = pyQuote(descr)
+	}
+	fortran := "False"
+	if hdr.Descr.Fortran {
+		fortran = "True"
 	}
 
-	n, err := io.Copy(w, buf)
+	dict := new(bytes.Buffer)
+	fmt.Fprintf(dict, "{'descr': %s, 'fortran_order': %s, 'shape': %s, }",
+		descr,
+		fortran,
+		shapeString(hdr.Descr.Shape),
+	)
+	// pad the header so the array data is aligned.
+	padding := (headerAlign - (hdrSize+dict.Len()+1)%headerAlign) % headerAlign
+	dict.Write(bytes.Repeat([]byte{'\x20'}, padding))
+	dict.WriteByte('\n')
+
+	// the header is always written out in little-endian.
+	order := binary.LittleEndian
+	buflen := int64(dict.Len())
+	buf := new(bytes.Buffer)
+	buf.Write(Magic[:])
+	buf.WriteByte(hdr.Major)
+	buf.WriteByte(hdr.Minor)
+	switch hdr.Major {
+	case 1:
+		if buflen > math.MaxUint16 {
+			return nil, fmt.Errorf("npy: header too large for version 1.0 (%d bytes)", buflen)
+		}
+		_ = binary.Write(buf, order, uint16(buflen))
+	case 2:
+		if buflen > math.MaxUint32 {
+			return nil, fmt.Errorf("npy: header too large for version 2.0 (%d bytes)", buflen)
+		}
+		_ = binary.Write(buf, order, uint32(buflen))
+	}
+	buf.Write(dict.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// writeRawHeader writes the provided encoded header into w.
+func writeRawHeader(w io.Writer, hdr Header, raw []byte) error {
+	want := int64(len(raw))
+	n, err := w.Write(raw)
 	if err != nil {
 		return err
 	}
-	if n < buflen {
+	if int64(n) < want {
 		return io.ErrShortWrite
 	}
 
+	if hw, ok := w.(headerWriter); ok {
+		hdr.DataOffset = want
+		hw.header(hdr)
+	}
+
 	return nil
 }
 
+// headerWriter is implemented by writers that keep track of the header
+// of the NumPy data file written to them.
+type headerWriter interface {
+	io.Writer
+	// header is called once the provided header has been written.
+	header(hdr Header)
+}
+
 func writeData(w io.Writer, rv reflect.Value, dt dType) error {
 	rt := rv.Type()
 	if rt == rtDense {
@@ -369,44 +881,6 @@ func writeData(w io.Writer, rv reflect.Value, dt dType) error {
 			}
 		}
 		return nil
-
-	case string:
-		o := []byte(v)
-		o = append(o, 0)
-		_, err := w.Write(o)
-		if err != nil {
-			return err
-		}
-		return nil
-
-	case []string:
-		n := dt.size
-		switch {
-		case dt.utf:
-			for _, str := range v {
-				o := make([]byte, n*utf8.UTFMax)
-				i := 0
-				for _, v := range str {
-					dt.order.PutUint32(o[i:i+utf8.UTFMax], uint32(v))
-					i += utf8.UTFMax
-				}
-				_, err := w.Write(o)
-				if err != nil {
-					return err
-				}
-			}
-
-		case !dt.utf:
-			o := make([]byte, len(v)*n)
-			for i, v := range v {
-				copy(o[i:i+n], []byte(v))
-			}
-			_, err := w.Write(o)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
 	}
 
 	switch rt.Kind() {
@@ -472,12 +946,9 @@ func dtypeFrom(rv reflect.Value, rt reflect.Type) (string, error) {
 		default:
 			return dtypeFrom(reflect.Value{}, et)
 		case reflect.String:
-			slice := rv.Slice(0, rt.Len()).Interface().([]string)
-			n := 0
-			for _, str := range slice {
-				if len(str) > n {
-					n = len(str)
-				}
+			n := 1
+			for i := 0; i < rv.Len(); i++ {
+				n = max(n, utf8.RuneCountInString(rv.Index(i).String()))
 			}
 			return fmt.Sprintf("<U%d", n), nil
 		}
@@ -488,18 +959,15 @@ func dtypeFrom(rv reflect.Value, rt reflect.Type) (string, error) {
 		default:
 			return dtypeFrom(reflect.Value{}, rt)
 		case reflect.String:
-			slice := rv.Interface().([]string)
-			n := 0
-			for _, str := range slice {
-				if len(str) > n {
-					n = len(str)
-				}
+			n := 1
+			for i := 0; i < rv.Len(); i++ {
+				n = max(n, utf8.RuneCountInString(rv.Index(i).String()))
 			}
 			return fmt.Sprintf("<U%d", n), nil
 		}
 
 	case reflect.String:
-		return fmt.Sprintf("<U%d", len(rv.Interface().(string))), nil
+		return fmt.Sprintf("<U%d", max(1, utf8.RuneCountInString(rv.String()))), nil
 
 	case reflect.Map, reflect.Chan, reflect.Interface, reflect.Struct:
 		return "", fmt.Errorf("npy: type %v not supported", rt)
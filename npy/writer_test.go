@@ -6,8 +6,12 @@ package npy
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -224,3 +228,771 @@ func TestShapeFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteArray(t *testing.T) {
+	fnames, err := filepath.Glob("../testdata/*.npy")
+	if err != nil {
+		t.Fatalf("could not glob testdata: %+v", err)
+	}
+
+	for _, fname := range fnames {
+		t.Run(filepath.Base(fname), func(t *testing.T) {
+			raw, err := os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			var want Array
+			err = r.Read(&want)
+			if err != nil {
+				t.Fatalf("could not read array: %+v", err)
+			}
+
+			buf := new(bytes.Buffer)
+			err = Write(buf, &want)
+			if err != nil {
+				t.Fatalf("could not write array: %+v", err)
+			}
+
+			rr, err := NewReader(buf)
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			if got, want := rr.Header.Descr, r.Header.Descr; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid header:\ngot= %+v\nwant=%+v", got, want)
+			}
+
+			var got Array
+			err = rr.Read(&got)
+			if err != nil {
+				t.Fatalf("could not read back array: %+v", err)
+			}
+
+			// compare string representations to handle NaNs.
+			if !reflect.DeepEqual(got, want) && got.String() != want.String() {
+				t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteArrayView(t *testing.T) {
+	descr, err := ParseDescr(">i4")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+
+	arr, err := NewArray(descr, []int{2, 3}, []int32{0, 1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatalf("could not create array: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		arr     func() (*Array, error)
+		fortran bool
+		shape   []int
+		want    []int32
+	}{
+		{
+			name:  "array",
+			arr:   func() (*Array, error) { return arr, nil },
+			shape: []int{2, 3},
+			want:  []int32{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name:    "transpose",
+			arr:     func() (*Array, error) { return arr.Transpose() },
+			fortran: true,
+			shape:   []int{3, 2},
+			want:    []int32{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name:  "slice",
+			arr:   func() (*Array, error) { return arr.Slice(1, 1, 3) },
+			shape: []int{2, 2},
+			want:  []int32{1, 2, 4, 5},
+		},
+		{
+			name: "transpose-slice",
+			arr: func() (*Array, error) {
+				v, err := arr.Transpose()
+				if err != nil {
+					return nil, err
+				}
+				return v.Slice(0, 1, 3)
+			},
+			shape: []int{2, 2},
+			want:  []int32{1, 4, 2, 5},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := tc.arr()
+			if err != nil {
+				t.Fatalf("could not create view: %+v", err)
+			}
+
+			buf := new(bytes.Buffer)
+			err = Write(buf, v)
+			if err != nil {
+				t.Fatalf("could not write array: %+v", err)
+			}
+
+			r, err := NewReader(buf)
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			if got, want := r.Header.Descr.Type, ">i4"; got != want {
+				t.Fatalf("invalid dtype: got=%q, want=%q", got, want)
+			}
+			if got, want := r.Header.Descr.Fortran, tc.fortran; got != want {
+				t.Fatalf("invalid fortran order: got=%v, want=%v", got, want)
+			}
+			if got, want := r.Header.Descr.Shape, tc.shape; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+
+			var got []int32
+			err = r.Read(&got)
+			if err != nil {
+				t.Fatalf("could not read back data: %+v", err)
+			}
+			if want := tc.want; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteArrayObject(t *testing.T) {
+	descr, err := ParseDescr("|O")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+
+	arr, err := NewArray(descr, []int{2, 2}, []any{
+		int64(1), "two",
+		[]any{3.5, nil}, map[string]any{"k": true},
+	})
+	if err != nil {
+		t.Fatalf("could not create array: %+v", err)
+	}
+	arr, err = arr.Transpose()
+	if err != nil {
+		t.Fatalf("could not transpose array: %+v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = Write(buf, arr)
+	if err != nil {
+		t.Fatalf("could not write array: %+v", err)
+	}
+
+	var got Array
+	err = Read(buf, &got)
+	if err != nil {
+		t.Fatalf("could not read back array: %+v", err)
+	}
+
+	native, err := got.Native()
+	if err != nil {
+		t.Fatalf("could not convert to native: %+v", err)
+	}
+
+	want := []any{
+		1, []any{3.5, nil},
+		"two", map[string]any{"k": true},
+	}
+	if !reflect.DeepEqual(native, want) {
+		t.Fatalf("invalid data:\ngot= %#v\nwant=%#v", native, want)
+	}
+	if got, want := got.Shape(), []int{2, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		// read -> write -> read an object array whose data type holds
+		// metadata, e.g. h5py.string_dtype().
+		descr := *dtypeMetaTests[0].want
+		arr, err := NewArray(descr, []int{2}, []any{"a", "bc"})
+		if err != nil {
+			t.Fatalf("could not create array: %+v", err)
+		}
+
+		buf := new(bytes.Buffer)
+		err = Write(buf, arr)
+		if err != nil {
+			t.Fatalf("could not write array: %+v", err)
+		}
+		var got Array
+		err = Read(bytes.NewReader(buf.Bytes()), &got)
+		if err != nil {
+			t.Fatalf("could not read back array: %+v", err)
+		}
+		if got, want := got.Descr().Metadata(), descr.Metadata(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid metadata:\ngot= %+v\nwant=%+v", got, want)
+		}
+
+		out := new(bytes.Buffer)
+		err = Write(out, &got)
+		if err != nil {
+			t.Fatalf("could not write array back: %+v", err)
+		}
+		if !bytes.Equal(out.Bytes(), buf.Bytes()) {
+			t.Fatalf("invalid read/write round-trip")
+		}
+	})
+}
+
+func TestWriteMatrix(t *testing.T) {
+	dense := mat.NewDense(3, 3, []float64{
+		1, 2, 3,
+		2, 4, 5,
+		3, 5, 6,
+	})
+	for _, tc := range []struct {
+		name string
+		m    mat.Matrix
+	}{
+		{"dense", dense},
+		{"transpose", mat.NewDense(2, 3, []float64{1, 2, 3, 4, 5, 6}).T()},
+		{"sym", mat.NewSymDense(3, []float64{1, 2, 3, 2, 4, 5, 3, 5, 6})},
+		{"tri-upper", mat.NewTriDense(3, mat.Upper, []float64{1, 2, 3, 0, 4, 5, 0, 0, 6})},
+		{"tri-lower", mat.NewTriDense(3, mat.Lower, []float64{1, 0, 0, 2, 4, 0, 3, 5, 6})},
+		{"band", mat.NewBandDense(3, 4, 1, 1, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := Write(buf, tc.m)
+			if err != nil {
+				t.Fatalf("could not write matrix: %+v", err)
+			}
+
+			var got mat.Dense
+			err = Read(buf, &got)
+			if err != nil {
+				t.Fatalf("could not read back matrix: %+v", err)
+			}
+			if !mat.Equal(&got, tc.m) {
+				t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", mat.Formatted(&got), mat.Formatted(tc.m))
+			}
+		})
+	}
+
+	t.Run("vec", func(t *testing.T) {
+		want := mat.NewVecDense(4, []float64{1, 2, 3, 4})
+		buf := new(bytes.Buffer)
+		err := Write(buf, want.SliceVec(1, 4))
+		if err != nil {
+			t.Fatalf("could not write vector: %+v", err)
+		}
+
+		r, err := NewReader(buf)
+		if err != nil {
+			t.Fatalf("could not create reader: %+v", err)
+		}
+		if got, want := r.Header.Descr.Shape, []int{3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+		}
+
+		var got mat.VecDense
+		err = r.Read(&got)
+		if err != nil {
+			t.Fatalf("could not read back vector: %+v", err)
+		}
+		if !mat.Equal(&got, want.SliceVec(1, 4)) {
+			t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", mat.Formatted(&got), mat.Formatted(want))
+		}
+	})
+
+	t.Run("vec-2d", func(t *testing.T) {
+		f, err := os.Open("../testdata/data_float64_2x3_corder.npy")
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+
+		var v mat.VecDense
+		err = Read(f, &v)
+		if err == nil {
+			t.Fatalf("expected an error reading a 2-d array into a vector")
+		}
+	})
+
+	t.Run("cdense", func(t *testing.T) {
+		want := mat.NewCDense(2, 3, []complex128{
+			1 + 1i, 2 - 2i, 3,
+			4i, 5 + 0.5i, -6,
+		})
+		buf := new(bytes.Buffer)
+		err := Write(buf, want)
+		if err != nil {
+			t.Fatalf("could not write matrix: %+v", err)
+		}
+
+		var got mat.CDense
+		err = Read(buf, &got)
+		if err != nil {
+			t.Fatalf("could not read back matrix: %+v", err)
+		}
+		if !mat.CEqual(&got, want) {
+			t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", got.RawCMatrix().Data, want.RawCMatrix().Data)
+		}
+	})
+
+	t.Run("cdense-c8-forder", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := WriteShaped(buf, []complex64{1, 4i, 2 - 2i, 5 + 0.5i, 3, -6}, []int{2, 3}, true)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+
+		var got mat.CDense
+		err = Read(buf, &got)
+		if err != nil {
+			t.Fatalf("could not read back matrix: %+v", err)
+		}
+		want := mat.NewCDense(2, 3, []complex128{
+			1, 2 - 2i, 3,
+			4i, 5 + 0.5i, -6,
+		})
+		if !mat.CEqual(&got, want) {
+			t.Fatalf("invalid matrix:\ngot= %v\nwant=%v", got.RawCMatrix().Data, want.RawCMatrix().Data)
+		}
+	})
+
+	t.Run("cdense-type-mismatch", func(t *testing.T) {
+		f, err := os.Open("../testdata/data_float64_2x3_corder.npy")
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+
+		var m mat.CDense
+		err = Read(f, &m)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, ErrTypeMismatch)
+		}
+	})
+}
+
+func TestWriteMatrices(t *testing.T) {
+	want := []mat.Dense{
+		*mat.NewDense(2, 3, []float64{0, 1, 2, 3, 4, 5}),
+		*mat.NewDense(2, 3, []float64{6, 7, 8, 9, 10, 11}),
+		*mat.NewDense(2, 3, []float64{12, 13, 14, 15, 16, 17}),
+		*mat.NewDense(2, 3, []float64{18, 19, 20, 21, 22, 23}),
+	}
+
+	for _, tc := range []struct {
+		name string
+		val  any
+	}{
+		{"values", want},
+		{"pointers", []*mat.Dense{&want[0], &want[1], &want[2], &want[3]}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := Write(buf, tc.val)
+			if err != nil {
+				t.Fatalf("could not write matrices: %+v", err)
+			}
+
+			r, err := NewReader(buf)
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+			if got, want := r.Header.Descr.Shape, []int{4, 2, 3}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+
+			var got []mat.Dense
+			err = r.Read(&got)
+			if err != nil {
+				t.Fatalf("could not read back matrices: %+v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("invalid number of matrices: got=%d, want=%d", len(got), len(want))
+			}
+			for i := range got {
+				if !mat.Equal(&got[i], &want[i]) {
+					t.Fatalf("invalid matrix %d:\ngot= %v\nwant=%v", i, mat.Formatted(&got[i]), mat.Formatted(&want[i]))
+				}
+			}
+		})
+	}
+
+	t.Run("forder", func(t *testing.T) {
+		var (
+			data  = make([]float64, 24)
+			shape = []int{4, 2, 3}
+		)
+		// lay out the matrices in Fortran-order.
+		for k := 0; k < 4; k++ {
+			for i := 0; i < 2; i++ {
+				for j := 0; j < 3; j++ {
+					data[k+4*i+8*j] = want[k].At(i, j)
+				}
+			}
+		}
+
+		buf := new(bytes.Buffer)
+		err := WriteShaped(buf, data, shape, true)
+		if err != nil {
+			t.Fatalf("could not write data: %+v", err)
+		}
+
+		var got []mat.Dense
+		err = Read(buf, &got)
+		if err != nil {
+			t.Fatalf("could not read back matrices: %+v", err)
+		}
+		for i := range got {
+			if !mat.Equal(&got[i], &want[i]) {
+				t.Fatalf("invalid matrix %d:\ngot= %v\nwant=%v", i, mat.Formatted(&got[i]), mat.Formatted(&want[i]))
+			}
+		}
+	})
+
+	t.Run("dims-mismatch", func(t *testing.T) {
+		err := Write(new(bytes.Buffer), []*mat.Dense{
+			mat.NewDense(2, 3, nil),
+			mat.NewDense(3, 2, nil),
+		})
+		if !errors.Is(err, ErrShapeMismatch) {
+			t.Fatalf("invalid error: got=%v, want=%v", err, ErrShapeMismatch)
+		}
+	})
+
+	t.Run("shape-mismatch", func(t *testing.T) {
+		f, err := os.Open("../testdata/data_float64_2x3_corder.npy")
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+
+		var ms []mat.Dense
+		err = Read(f, &ms)
+		if err == nil {
+			t.Fatalf("expected an error reading a 2-d array into a batch of matrices")
+		}
+	})
+}
+
+func TestWriteStrings(t *testing.T) {
+	u32 := func(s string, n int) []byte {
+		o := make([]byte, 4*n)
+		i := 0
+		for _, r := range s {
+			o[i] = byte(r)
+			o[i+1] = byte(r >> 8)
+			o[i+2] = byte(r >> 16)
+			i += 4
+		}
+		return o
+	}
+	cat := func(vs ...[]byte) []byte {
+		return bytes.Join(vs, nil)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		val   any
+		opts  []WriteOption
+		descr string
+		shape []int
+		raw   []byte
+		err   bool
+	}{
+		{
+			name:  "scalar",
+			val:   "héllo",
+			descr: "<U5",
+			raw:   u32("héllo", 5),
+		},
+		{
+			name:  "empty",
+			val:   "",
+			descr: "<U1",
+			raw:   u32("", 1),
+		},
+		{
+			name:  "slice",
+			val:   []string{"hello", "世界"},
+			descr: "<U5",
+			shape: []int{2},
+			raw:   cat(u32("hello", 5), u32("世界", 5)),
+		},
+		{
+			name:  "array",
+			val:   [2]string{"a", "世界"},
+			descr: "<U2",
+			shape: []int{2},
+			raw:   cat(u32("a", 2), u32("世界", 2)),
+		},
+		{
+			name:  "slice-bytes-kind",
+			val:   []string{"ab", "é"},
+			opts:  []WriteOption{WithStringKind('S')},
+			descr: "|S2",
+			shape: []int{2},
+			raw:   []byte("ab\xc3\xa9"),
+		},
+		{
+			name:  "bytes",
+			val:   [][]byte{[]byte("abc"), []byte("d")},
+			descr: "|S3",
+			shape: []int{2},
+			raw:   []byte("abcd\x00\x00"),
+		},
+		{
+			name:  "bytes-unicode-kind",
+			val:   [][]byte{[]byte("ab")},
+			opts:  []WriteOption{WithStringKind('U')},
+			descr: "<U2",
+			shape: []int{1},
+			raw:   u32("ab", 2),
+		},
+		{
+			name:  "width",
+			val:   []string{"ab", "世界"},
+			opts:  []WriteOption{WithStringWidth(4)},
+			descr: "<U4",
+			shape: []int{2},
+			raw:   cat(u32("ab", 4), u32("世界", 4)),
+		},
+		{
+			name: "width-too-long",
+			val:  []string{"abc", "世界"},
+			opts: []WriteOption{WithStringWidth(2)},
+			err:  true,
+		},
+		{
+			name:  "width-truncate",
+			val:   []string{"abc", "世界!"},
+			opts:  []WriteOption{WithStringWidth(2), WithStringTruncate(true)},
+			descr: "<U2",
+			shape: []int{2},
+			raw:   cat(u32("ab", 2), u32("世界", 2)),
+		},
+		{
+			name:  "width-truncate-bytes",
+			val:   [][]byte{[]byte("abc"), []byte("d")},
+			opts:  []WriteOption{WithStringWidth(2), WithStringTruncate(true)},
+			descr: "|S2",
+			shape: []int{2},
+			raw:   []byte("abd\x00"),
+		},
+		{
+			name: "invalid-kind",
+			val:  "abc",
+			opts: []WriteOption{WithStringKind('x')},
+			err:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := Write(buf, tc.val, tc.opts...)
+			switch {
+			case err != nil && tc.err:
+				return
+			case err != nil:
+				t.Fatalf("could not write strings: %+v", err)
+			case tc.err:
+				t.Fatalf("expected an error")
+			}
+
+			hdr, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("could not read header: %+v", err)
+			}
+			if got, want := hdr.Descr.Type, tc.descr; got != want {
+				t.Fatalf("invalid descr:\ngot= %q\nwant=%q", got, want)
+			}
+			if got, want := hdr.Descr.Shape, tc.shape; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid shape:\ngot= %v\nwant=%v", got, want)
+			}
+			if got, want := buf.Bytes()[hdr.DataOffset:], tc.raw; !bytes.Equal(got, want) {
+				t.Fatalf("invalid data:\ngot= %q\nwant=%q", got, want)
+			}
+		})
+	}
+
+	t.Run("no-modify", func(t *testing.T) {
+		strs := []string{"abc", "def"}
+		err := Write(io.Discard, strs, WithStringWidth(1), WithStringTruncate(true))
+		if err != nil {
+			t.Fatalf("could not write strings: %+v", err)
+		}
+		if got, want := strs, []string{"abc", "def"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("input strings modified:\ngot= %q\nwant=%q", got, want)
+		}
+	})
+}
+
+func TestWriteRaw(t *testing.T) {
+	for _, fname := range []string{
+		"../testdata/data_float64_2x3_forder.npy",
+		"../testdata/data_int16_scalar_corder.npy",
+		"../testdata/ragged-array.npy",
+	} {
+		t.Run(filepath.Base(fname), func(t *testing.T) {
+			raw, err := os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("could not create reader: %+v", err)
+			}
+
+			buf := new(bytes.Buffer)
+			err = WriteRaw(buf, r.Header, r.Payload())
+			if err != nil {
+				t.Fatalf("could not write raw data: %+v", err)
+			}
+
+			var got, want Array
+			err = Read(buf, &got)
+			if err != nil {
+				t.Fatalf("could not read back data: %+v", err)
+			}
+			err = Read(bytes.NewReader(raw), &want)
+			if err != nil {
+				t.Fatalf("could not read reference data: %+v", err)
+			}
+			if !reflect.DeepEqual(got.Data(), want.Data()) ||
+				!reflect.DeepEqual(got.Shape(), want.Shape()) ||
+				got.Fortran() != want.Fortran() {
+				t.Fatalf("invalid round-trip:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		// hide the size of the payload, to exercise the streaming path.
+		stream := func(p []byte) io.Reader { return io.MultiReader(bytes.NewReader(p)) }
+
+		for _, tc := range []struct {
+			name    string
+			major   byte
+			descr   string
+			shape   []int
+			payload io.Reader
+			err     error
+			elem    int  // index of the failing element, for payload errors.
+			written bool // whether the header may have been written out.
+		}{
+			{"short", 0, "<i4", []int{2}, bytes.NewReader(make([]byte, 7)), io.ErrUnexpectedEOF, 1, false},
+			{"long", 0, "<i4", []int{2}, bytes.NewReader(make([]byte, 9)), ErrPayload, 2, false},
+			{"short-section", 0, "<i4", []int{2}, io.NewSectionReader(bytes.NewReader(make([]byte, 7)), 0, 7), io.ErrUnexpectedEOF, 1, false},
+			{"short-stream", 0, "<i4", []int{2}, stream(make([]byte, 7)), io.ErrUnexpectedEOF, 1, true},
+			{"long-stream", 0, "<i4", []int{2}, stream(make([]byte, 9)), ErrPayload, 2, true},
+			{"short-limited", 0, "<i4", []int{2}, io.LimitReader(stream(make([]byte, 7)), 8), io.ErrUnexpectedEOF, 1, true},
+			{"descr", 0, "<x4", []int{2}, bytes.NewReader(make([]byte, 8)), nil, 0, false},
+			{"negative-shape", 0, "<i4", []int{-2}, bytes.NewReader(nil), ErrShapeMismatch, 0, false},
+			{"shape-overflow", 0, "<i4", []int{1 << 62, 4}, bytes.NewReader(nil), ErrShapeMismatch, 0, false},
+			{"size-overflow", 0, "<i8", []int{1 << 62}, bytes.NewReader(nil), ErrShapeMismatch, 0, false},
+			{"version", 4, "<i4", []int{2}, bytes.NewReader(make([]byte, 8)), nil, 0, false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				var hdr Header
+				hdr.Major = tc.major
+				hdr.Descr.Type = tc.descr
+				hdr.Descr.Shape = tc.shape
+
+				buf := new(bytes.Buffer)
+				err := WriteRaw(buf, hdr, tc.payload)
+				switch {
+				case err == nil:
+					t.Fatalf("expected an error")
+				case tc.err != nil && !errors.Is(err, tc.err):
+					t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.err)
+				}
+				if errors.Is(tc.err, ErrPayload) || errors.Is(tc.err, io.ErrUnexpectedEOF) {
+					var perr *PayloadError
+					if !errors.As(err, &perr) {
+						t.Fatalf("invalid error type: %T", err)
+					}
+					if got, want := perr.Element, tc.elem; got != want {
+						t.Fatalf("invalid element:\ngot= %d\nwant=%d", got, want)
+					}
+				}
+				if !tc.written && buf.Len() != 0 {
+					t.Fatalf("invalid output: %d bytes written out", buf.Len())
+				}
+			})
+		}
+	})
+
+	t.Run("default-version", func(t *testing.T) {
+		var hdr Header
+		hdr.Descr.Type = "<i4"
+		hdr.Descr.Shape = []int{2}
+
+		buf := new(bytes.Buffer)
+		err := WriteRaw(buf, hdr, bytes.NewReader([]byte{1, 0, 0, 0, 2, 0, 0, 0}))
+		if err != nil {
+			t.Fatalf("could not write raw data: %+v", err)
+		}
+		r, err := NewReader(buf)
+		if err != nil {
+			t.Fatalf("could not create reader: %+v", err)
+		}
+		if got, want := r.Header.Major, newHeader().Major; got != want {
+			t.Fatalf("invalid major version:\ngot= %d\nwant=%d", got, want)
+		}
+		var data []int32
+		err = r.Read(&data)
+		if err != nil {
+			t.Fatalf("could not read data: %+v", err)
+		}
+		if got, want := data, []int32{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid data:\ngot= %v\nwant=%v", got, want)
+		}
+	})
+}
+
+func TestWriteHeaderAlign(t *testing.T) {
+	for _, tc := range []struct {
+		major byte
+		descr string
+		shape []int
+	}{
+		{1, "<f8", nil},
+		{1, "<f8", []int{1000}},
+		{1, "|u1", []int{2, 3, 4, 5, 6, 7, 8}},
+		{1, "[('x', '<f8'), ('y', '<i4')]", []int{12}},
+		{2, "<f8", []int{1000}},
+		{2, "<c16", []int{1 << 20, 1 << 20}},
+	} {
+		t.Run(fmt.Sprintf("v%d-%s-%v", tc.major, tc.descr, tc.shape), func(t *testing.T) {
+			hdr := newTestHeader(tc.descr, false, tc.shape)
+			hdr.Major = tc.major
+
+			buf := new(bytes.Buffer)
+			err := writeHeader(buf, hdr)
+			if err != nil {
+				t.Fatalf("could not write header: %+v", err)
+			}
+			if got := buf.Len(); got%64 != 0 {
+				t.Fatalf("header size %d is not 64-byte aligned", got)
+			}
+			if got := buf.Bytes()[buf.Len()-1]; got != '\n' {
+				t.Fatalf("invalid header terminator %q", got)
+			}
+
+			got, err := ReadHeader(buf)
+			if err != nil {
+				t.Fatalf("could not read header: %+v", err)
+			}
+			if got.DataOffset%64 != 0 {
+				t.Fatalf("array data offset %d is not 64-byte aligned", got.DataOffset)
+			}
+		})
+	}
+}
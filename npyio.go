@@ -11,6 +11,9 @@
 // # Supported types
 //
 // npyio supports r/w of scalars, arrays, slices and gonum/mat.Dense.
+// N-dimensional numpy-arrays are supported through npy.Array, npy.Tensor[T]
+// and (for reading) nested slices and arrays.
+// See the npy package for the complete list of supported types.
 // Supported scalars are:
 //   - bool,
 //   - (u)int{8,16,32,64},
@@ -79,6 +82,24 @@ var (
 	// reliably (de)serialized.
 	ErrInvalidType = npy.ErrInvalidType
 
+	// ErrShapeMismatch is the error returned when an array shape is
+	// invalid, or when it does not match the shape of the user provided
+	// Go value.
+	ErrShapeMismatch = npy.ErrShapeMismatch
+
+	// ErrPayload is the error returned by Reader when the array data of a
+	// NumPy data file can not be read, e.g. because the file is truncated.
+	ErrPayload = npy.ErrPayload
+
+	// ErrLimitExceeded is the error returned by Reader when the header
+	// or the array data of a NumPy data file exceed the configured
+	// resource limits.
+	ErrLimitExceeded = npy.ErrLimitExceeded
+
+	// ErrLongDoubleNaN is the error returned by Reader when a NaN
+	// extended precision (longdouble) value is read into a *big.Float.
+	ErrLongDoubleNaN = npy.ErrLongDoubleNaN
+
 	// Magic header present at the start of a NumPy data file format.
 	// See https://numpy.org/neps/nep-0001-npy-format.html
 	Magic = npy.Magic
@@ -87,12 +108,38 @@ var (
 // Header describes the data content of a NumPy data file.
 type Header = npy.Header
 
+// HeaderError describes an invalid NumPy data file header.
+type HeaderError = npy.HeaderError
+
+// TypeMismatchError describes a Go value that can not hold the array data
+// of a NumPy data file.
+type TypeMismatchError = npy.TypeMismatchError
+
+// ShapeError describes a Go value that can not hold the array data of a
+// NumPy data file, because of the array shape.
+type ShapeError = npy.ShapeError
+
+// PayloadError describes a failure to read the array data of a NumPy data
+// file.
+type PayloadError = npy.PayloadError
+
+// LimitError describes a NumPy data file whose header or array data
+// exceed the configured resource limits.
+type LimitError = npy.LimitError
+
+// DescrError describes a data type description that could not be parsed,
+// or array data that could not be encoded or decoded with it.
+type DescrError = npy.DescrError
+
 // Reader reads data from a NumPy data file.
 type Reader = npy.Reader
 
+// ReadOption configures how NumPy data files are read.
+type ReadOption = npy.ReadOption
+
 // NewReader creates a new NumPy data file format reader.
-func NewReader(r io.Reader) (*Reader, error) {
-	return npy.NewReader(r)
+func NewReader(r io.Reader, opts ...ReadOption) (*Reader, error) {
+	return npy.NewReader(r, opts...)
 }
 
 // Read reads the data from the r NumPy data file io.Reader, into the
@@ -103,11 +150,16 @@ func NewReader(r io.Reader) (*Reader, error) {
 // If a *mat.Dense matrix is passed to Read, the numpy-array data is loaded
 // into the Dense matrix, honouring Fortran/C-order and dimensions/shape
 // parameters.
-//
-// Only numpy-arrays with up to 2 dimensions are supported.
-// Only numpy-arrays with elements convertible to float64 are supported.
-func Read(r io.Reader, ptr interface{}) error {
-	return npy.Read(r, ptr)
+// N-dimensional numpy-arrays can be loaded into an *npy.Array, a
+// *npy.Tensor[T] or nested slices and arrays (e.g. *[][]float64.)
+// See npy.Read for the complete list of supported values.
+func Read(r io.Reader, ptr interface{}, opts ...ReadOption) error {
+	return npy.Read(r, ptr, opts...)
+}
+
+// ReadHeader reads the header of the r NumPy data file io.Reader.
+func ReadHeader(r io.Reader, opts ...ReadOption) (Header, error) {
+	return npy.ReadHeader(r, opts...)
 }
 
 // TypeFrom returns the reflect.Type corresponding to the numpy-dtype string, if any.
@@ -123,6 +175,9 @@ func TypeFrom(dtype string) reflect.Type {
 //   - if val is a mat.Dense, the correct shape will be transmitted. (ie: (nrows, ncols))
 //
 // The data-array will always be written out in C-order (row-major).
-func Write(w io.Writer, val interface{}) error {
-	return npy.Write(w, val)
+func Write(w io.Writer, val interface{}, opts ...WriteOption) error {
+	return npy.Write(w, val, opts...)
 }
+
+// WriteOption configures how NumPy data files are written.
+type WriteOption = npy.WriteOption
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"gonum.org/v1/gonum/mat"
 
@@ -92,7 +93,14 @@ func ExampleRead() {
 }
 
 func Example_partialRead() {
-	out, err := os.Create("data.npy")
+	dir, err := os.MkdirTemp("", "npyio-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "data.npy")
+	out, err := os.Create(fname)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -110,7 +118,7 @@ func Example_partialRead() {
 		log.Fatal(err)
 	}
 
-	in, err := os.Open("data.npy")
+	in, err := os.Open(fname)
 	if err != nil {
 		log.Fatal(err)
 	}
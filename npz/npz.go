@@ -12,8 +12,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
+// npySuffix is the suffix of the names of the npz archive entries.
+const npySuffix = ".npy"
+
+// keyOf returns the numpy-style name of the provided npz archive entry,
+// i.e. without its ".npy" suffix.
+func keyOf(name string) string {
+	return strings.TrimSuffix(name, npySuffix)
+}
+
 func sizeof(r io.ReaderAt) (int64, error) {
 	switch r := r.(type) {
 	case interface{ Stat() (os.FileInfo, error) }:
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/sbinet/npyio/npz"
 )
@@ -39,8 +40,8 @@ func ExampleOpen() {
 	fmt.Printf("arr1: %v\n", f1)
 
 	// Output:
-	// arr1.npy: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[6 1]}}
-	// arr0.npy: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[2 3]}}
+	// arr1: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[6 1]}}
+	// arr0: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[2 3]}}
 	// arr0: [0 1 2 3 4 5]
 	// arr1: [0 1 2 3 4 5]
 }
@@ -82,8 +83,8 @@ func ExampleReader() {
 	fmt.Printf("arr1: %v\n", f1)
 
 	// Output:
-	// arr1.npy: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[6 1]}}
-	// arr0.npy: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[2 3]}}
+	// arr1: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[6 1]}}
+	// arr0: Header{Major:1, Minor:0, Descr:{Type:<f8, Fortran:false, Shape:[2 3]}}
 	// arr0: [0 1 2 3 4 5]
 	// arr1: [0 1 2 3 4 5]
 }
@@ -116,7 +117,14 @@ func ExampleRead() {
 }
 
 func ExampleCreate() {
-	f, err := npz.Create("out.npz")
+	dir, err := os.MkdirTemp("", "npz-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "out.npz")
+	f, err := npz.Create(fname)
 	if err != nil {
 		log.Fatalf("could not create npz file: %+v", err)
 	}
@@ -141,7 +149,14 @@ func ExampleCreate() {
 }
 
 func ExampleWriter() {
-	f, err := os.Create("out.npz")
+	dir, err := os.MkdirTemp("", "npz-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "out.npz")
+	f, err := os.Create(fname)
 	if err != nil {
 		log.Fatalf("could not create npz file: %+v", err)
 	}
@@ -174,7 +189,14 @@ func ExampleWriter() {
 }
 
 func ExampleWrite() {
-	err := npz.Write("out.npz", map[string]interface{}{
+	dir, err := os.MkdirTemp("", "npz-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "out.npz")
+	err = npz.Write(fname, map[string]interface{}{
 		"arr0.npy": []float64{0, 1, 2, 3, 4, 5},
 		"arr1.npy": []float32{0, 1, 2, 3, 4, 5},
 	})
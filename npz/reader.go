@@ -6,6 +6,8 @@ package npz
 
 import (
 	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,15 +15,21 @@ import (
 	"github.com/sbinet/npyio/npy"
 )
 
+// ErrCompressed is the error returned by Reader.SectionReader for
+// compressed archive entries, whose data can not be accessed directly.
+var ErrCompressed = errors.New("npz: compressed entry")
+
 // Read reads the item named name from the reader r and
 // stores the extracted data into ptr.
-func Read(r io.ReaderAt, name string, ptr interface{}) error {
+//
+// The provided options are used to configure how the item is decoded.
+func Read(r io.ReaderAt, name string, ptr interface{}, opts ...npy.ReadOption) error {
 	sz, err := sizeof(r)
 	if err != nil {
 		return fmt.Errorf("npz: could not retrieve size of reader: %w", err)
 	}
 
-	rz, err := NewReader(r, sz)
+	rz, err := NewReader(r, sz, opts...)
 	if err != nil {
 		return fmt.Errorf("npz: could not create npz reader: %w", err)
 	}
@@ -40,10 +48,15 @@ type Reader struct {
 	rc io.Closer
 
 	keys []string
+	opts []npy.ReadOption
 }
 
 // Open opens the named compressed NumPy data file for reading.
-func Open(name string) (*Reader, error) {
+//
+// The provided options are used to configure how the NumPy arrays
+// are decoded, e.g. npy.WithMaxBytes to bound the memory used to decode
+// untrusted archives.
+func Open(name string, opts ...npy.ReadOption) (*Reader, error) {
 	r, err := os.Open(name)
 	if err != nil {
 		return nil, fmt.Errorf("npz: could not open %q: %w", name, err)
@@ -66,7 +79,7 @@ func Open(name string) (*Reader, error) {
 
 	keys := make([]string, len(rz.File))
 	for i, f := range rz.File {
-		keys[i] = f.Name
+		keys[i] = keyOf(f.Name)
 	}
 
 	return &Reader{
@@ -74,12 +87,17 @@ func Open(name string) (*Reader, error) {
 		rz:   rz,
 		rc:   r,
 		keys: keys,
+		opts: opts,
 	}, nil
 }
 
 // NewReader reads the compressed NumPy data from r, which is assumed
 // to have the given size in bytes.
-func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+//
+// The provided options are used to configure how the NumPy arrays
+// are decoded, e.g. npy.WithMaxBytes to bound the memory used to decode
+// untrusted archives.
+func NewReader(r io.ReaderAt, size int64, opts ...npy.ReadOption) (*Reader, error) {
 	rz, err := zip.NewReader(r, size)
 	if err != nil {
 		return nil, fmt.Errorf("npz: could not create zip reader: %w", err)
@@ -87,13 +105,14 @@ func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
 
 	keys := make([]string, len(rz.File))
 	for i, f := range rz.File {
-		keys[i] = f.Name
+		keys[i] = keyOf(f.Name)
 	}
 
 	return &Reader{
 		r:    r,
 		rz:   rz,
 		keys: keys,
+		opts: opts,
 	}, nil
 }
 
@@ -116,6 +135,7 @@ func (r *Reader) Close() error {
 }
 
 // Keys returns the names of the NumPy data arrays.
+// Like numpy.load, the names are returned without their ".npy" suffix.
 func (r *Reader) Keys() []string {
 	return r.keys
 }
@@ -130,25 +150,72 @@ func (r *Reader) Header(name string) *npy.Header {
 }
 
 // Open opens the named npy section in the npz archive.
+// Like numpy.load, the name may omit the ".npy" suffix of the entry.
 func (r *Reader) Open(name string) (io.ReadCloser, error) {
 	return r.open(name)
 }
 
 func (r *Reader) open(name string) (io.ReadCloser, error) {
+	f := r.file(name)
+	if f == nil {
+		return nil, fmt.Errorf("npz: could not find %q", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"npz: could not open item %q from npz: %w",
+			name, err,
+		)
+	}
+	return rc, nil
+}
+
+// file returns the archive entry with the provided name, or with the
+// provided name and the ".npy" suffix, like numpy.load does.
+func (r *Reader) file(name string) *zip.File {
 	for _, f := range r.rz.File {
-		if f.Name != name {
-			continue
+		if f.Name == name {
+			return f
 		}
-		rc, err := f.Open()
-		if err != nil {
-			return nil, fmt.Errorf(
-				"npz: could not open item %q from npz: %w",
-				name, err,
-			)
+	}
+	for _, f := range r.rz.File {
+		if f.Name == name+npySuffix {
+			return f
 		}
-		return rc, nil
 	}
-	return nil, fmt.Errorf("npz: could not find %q", name)
+	return nil
+}
+
+// SectionReader returns a reader over the data (header and array data) of
+// the named npy section in the npz archive, and the offset of its array
+// data in the underlying io.ReaderAt (e.g. to memory-map the array data of
+// an npz file.)
+// Like numpy.load, the name may omit the ".npy" suffix of the entry.
+//
+// The returned reader reads directly from the underlying io.ReaderAt,
+// without checking the CRC-32 of the entry.
+// SectionReader returns ErrCompressed if the entry is not stored
+// uncompressed (see WithCompression.)
+func (r *Reader) SectionReader(name string) (*io.SectionReader, int64, error) {
+	f := r.file(name)
+	if f == nil {
+		return nil, 0, fmt.Errorf("npz: could not find %q", name)
+	}
+	if f.Method != zip.Store {
+		return nil, 0, fmt.Errorf("npz: could not access item %q (method=%d): %w", name, f.Method, ErrCompressed)
+	}
+	beg, err := f.DataOffset()
+	if err != nil {
+		return nil, 0, fmt.Errorf("npz: could not locate item %q: %w", name, err)
+	}
+
+	sr := io.NewSectionReader(r.r, beg, int64(f.UncompressedSize64))
+	hdr, err := npy.ReadHeader(io.NewSectionReader(sr, 0, sr.Size()), r.opts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("npz: could not read npy header of %q: %w", name, err)
+	}
+
+	return sr, beg + hdr.DataOffset, nil
 }
 
 func (r *Reader) get(name string) (*ritem, error) {
@@ -156,7 +223,10 @@ func (r *Reader) get(name string) (*ritem, error) {
 	if err != nil {
 		return nil, err
 	}
-	rp, err := npy.NewReader(rc)
+	// bound the npy data to the declared size of the entry, so the npy
+	// reader can check the array size against it.
+	lr := &io.LimitedReader{R: rc, N: int64(r.file(name).UncompressedSize64)}
+	rp, err := npy.NewReader(lr, r.opts...)
 	if err != nil {
 		_ = rc.Close()
 		return nil, fmt.Errorf(
@@ -197,17 +267,48 @@ func (r *ritem) Close() error {
 }
 
 // Read reads the named NumPy array data into the provided pointer.
+// Like numpy.load, the name may omit the ".npy" suffix of the entry.
 //
 // Read returns an error if the on-disk data type and the provided one
 // don't match.
 func (r *Reader) Read(name string, ptr interface{}) error {
+	return r.ReadContext(context.Background(), name, ptr)
+}
+
+// ReadRows reads the rows [beg, end) of the named NumPy array data into
+// the provided pointer, like npy.ReadRows.
+// Like numpy.load, the name may omit the ".npy" suffix of the entry.
+//
+// Only the npy header and the requested rows are read from the underlying
+// io.ReaderAt, without decompressing nor scanning the whole entry.
+// ReadRows returns ErrCompressed if the entry is not stored uncompressed:
+// compressed entries can only be read as a whole, with Read.
+func (r *Reader) ReadRows(name string, ptr interface{}, beg, end int) error {
+	sr, _, err := r.SectionReader(name)
+	if err != nil {
+		return fmt.Errorf("npz: could not read %q: %w", name, err)
+	}
+
+	err = npy.ReadRows(sr, ptr, beg, end, r.opts...)
+	if err != nil {
+		return fmt.Errorf("npz: could not read %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// ReadContext reads the named NumPy array data into the provided pointer,
+// like Read.
+// ReadContext checks for the cancellation of the provided context while
+// reading the array data.
+func (r *Reader) ReadContext(ctx context.Context, name string, ptr interface{}) error {
 	it, err := r.get(name)
 	if err != nil {
 		return fmt.Errorf("npz: could not read %q: %w", name, err)
 	}
 	defer it.Close()
 
-	err = it.rp.Read(ptr)
+	err = it.rp.ReadContext(ctx, ptr)
 	if err != nil {
 		return fmt.Errorf("npz: could not read %q: %w", name, err)
 	}
@@ -5,19 +5,27 @@
 package npz
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/sbinet/npyio/npy"
 	"gonum.org/v1/gonum/mat"
 )
 
 func TestReader(t *testing.T) {
 	want := map[string]map[bool]*mat.Dense{
-		"arr0.npy": {
+		"arr0": {
 			false: mat.NewDense(2, 3, []float64{0, 1, 2, 3, 4, 5}), // row-major
 			true:  mat.NewDense(2, 3, []float64{0, 2, 4, 1, 3, 5}), // col-major
 		},
-		"arr1.npy": {
+		"arr1": {
 			false: mat.NewDense(6, 1, []float64{0, 1, 2, 3, 4, 5}),
 			true:  mat.NewDense(6, 1, []float64{0, 1, 2, 3, 4, 5}),
 		},
@@ -54,3 +62,128 @@ func TestReader(t *testing.T) {
 		})
 	}
 }
+
+func TestReaderSectionReader(t *testing.T) {
+	data := make([]float64, 1000)
+	for i := range data {
+		data[i] = float64(i)
+	}
+	raw := new(bytes.Buffer)
+	err := npy.Write(raw, data)
+	if err != nil {
+		t.Fatalf("could not write npy data: %+v", err)
+	}
+	hdr, err := npy.ReadHeader(bytes.NewReader(raw.Bytes()))
+	if err != nil {
+		t.Fatalf("could not read npy header: %+v", err)
+	}
+	if hdr.DataOffset%64 != 0 {
+		t.Fatalf("array data offset %d is not 64-byte aligned", hdr.DataOffset)
+	}
+
+	buf := new(bytes.Buffer)
+	wz := NewWriter(buf, WithCompression(zip.Store))
+	err = wz.Write("stored", data)
+	if err != nil {
+		t.Fatalf("could not write stored entry: %+v", err)
+	}
+	err = wz.Write("deflated", data, WithCompression(zip.Deflate))
+	if err != nil {
+		t.Fatalf("could not write deflated entry: %+v", err)
+	}
+	err = wz.Close()
+	if err != nil {
+		t.Fatalf("could not close archive: %+v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("could not open archive: %+v", err)
+	}
+
+	sr, off, err := r.SectionReader("stored")
+	if err != nil {
+		t.Fatalf("could not access stored entry: %+v", err)
+	}
+	got, err := io.ReadAll(sr)
+	if err != nil {
+		t.Fatalf("could not read stored entry: %+v", err)
+	}
+	if !bytes.Equal(got, raw.Bytes()) {
+		t.Fatalf("invalid stored entry data")
+	}
+	payload := raw.Bytes()[hdr.DataOffset:]
+	if got := buf.Bytes()[off : off+int64(len(payload))]; !bytes.Equal(got, payload) {
+		t.Fatalf("invalid array data offset %d", off)
+	}
+
+	_, _, err = r.SectionReader("deflated")
+	if !errors.Is(err, ErrCompressed) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrCompressed)
+	}
+	_, _, err = r.SectionReader("missing")
+	if err == nil {
+		t.Fatalf("expected an error for a missing entry")
+	}
+
+	for _, name := range []string{"stored", "stored.npy"} {
+		var got []float64
+		err = r.ReadRows(name, &got, 10, 20)
+		if err != nil {
+			t.Fatalf("could not read %q rows: %+v", name, err)
+		}
+		if !reflect.DeepEqual(got, data[10:20]) {
+			t.Fatalf("invalid %q rows:\ngot= %v\nwant=%v", name, got, data[10:20])
+		}
+
+		var arr npy.Array
+		err = r.ReadRows(name, &arr, 0, len(data))
+		if err != nil {
+			t.Fatalf("could not read %q array: %+v", name, err)
+		}
+		if !reflect.DeepEqual(arr.Data(), data) {
+			t.Fatalf("invalid %q array data", name)
+		}
+	}
+
+	err = r.ReadRows("deflated", new([]float64), 10, 20)
+	if !errors.Is(err, ErrCompressed) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, ErrCompressed)
+	}
+	err = r.ReadRows("stored", new([]float64), 10, 2000)
+	if !errors.Is(err, npy.ErrShapeMismatch) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, npy.ErrShapeMismatch)
+	}
+}
+
+func FuzzNewReader(f *testing.F) {
+	fnames, err := filepath.Glob("../testdata/*.npz")
+	if err != nil {
+		f.Fatalf("could not find seed files: %+v", err)
+	}
+	for _, fname := range fnames {
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			f.Fatalf("could not read seed file: %+v", err)
+		}
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		r, err := NewReader(
+			bytes.NewReader(raw), int64(len(raw)),
+			npy.WithMaxElements(1<<16), npy.WithMaxBytes(1<<20),
+		)
+		if err != nil {
+			return
+		}
+		for _, name := range r.Keys() {
+			_ = r.Header(name)
+			var arr npy.Array
+			_ = r.Read(name, &arr)
+			var m mat.Dense
+			_ = r.Read(name, &m)
+			_ = r.ReadRows(name, &arr, 0, 1)
+		}
+	})
+}
@@ -6,19 +6,113 @@ package npz
 
 import (
 	"archive/zip"
+	"compress/flate"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/sbinet/npyio/npy"
 )
 
+// WriteOption configures how npz archives and their entries are written.
+type WriteOption func(*writeConfig)
+
+type writeConfig struct {
+	method uint16                    // compression method of the entries.
+	level  int                       // Deflate compression level.
+	comps  map[uint16]zip.Compressor // registered compressors.
+	suffix bool                      // whether to append ".npy" to entry names.
+}
+
+func newWriteConfig(opts []WriteOption) writeConfig {
+	cfg := writeConfig{
+		method: zip.Deflate,
+		level:  flate.DefaultCompression,
+		suffix: true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithCompression sets the compression method of the archive entries,
+// e.g. zip.Store (as numpy.savez does) or zip.Deflate (as
+// numpy.savez_compressed does.)
+// Other methods need a compressor, registered with WithCompressor.
+// The default is zip.Deflate, for all the functions and methods of this
+// package: use WithCompression(zip.Store) to write archives like
+// numpy.savez does.
+//
+// Stored entries are not compressed: their array data can be accessed
+// directly inside the archive.
+func WithCompression(method uint16) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.method = method
+	}
+}
+
+// WithCompressionLevel sets the compression level of the entries
+// compressed with zip.Deflate, from flate.BestSpeed to
+// flate.BestCompression.
+// The default is flate.DefaultCompression.
+func WithCompressionLevel(level int) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.level = level
+	}
+}
+
+// WithCompressor registers the compressor for the provided compression
+// method, overriding the default compressor for that method.
+// Compressors can only be registered for a whole archive, i.e. when
+// creating a Writer.
+func WithCompressor(method uint16, comp zip.Compressor) WriteOption {
+	return func(cfg *writeConfig) {
+		if cfg.comps == nil {
+			cfg.comps = make(map[uint16]zip.Compressor)
+		}
+		cfg.comps[method] = comp
+	}
+}
+
+// WithNpySuffix sets whether the ".npy" suffix is appended to the names of
+// the entries that lack it, like numpy.savez does.
+// numpy.load exposes the entries without their ".npy" suffix.
+// The default is true.
+func WithNpySuffix(v bool) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.suffix = v
+	}
+}
+
 // Write writes the values vs to the named npz archive file.
 //
+// Unlike numpy.savez, the archive entries are compressed with zip.Deflate,
+// unless other options are provided (see WithCompression.)
 // The data-array will always be written out in C-order (row-major).
-func Write(name string, vs map[string]interface{}) error {
-	w, err := Create(name)
+func Write(name string, vs map[string]interface{}, opts ...WriteOption) error {
+	return WriteContext(context.Background(), name, vs, opts...)
+}
+
+// WriteCompressed writes the values vs to the named npz archive file,
+// like Write.
+//
+// Like numpy.savez_compressed, the archive entries are compressed with
+// zip.Deflate, unless other options are provided.
+func WriteCompressed(name string, vs map[string]interface{}, opts ...WriteOption) error {
+	opts = append([]WriteOption{WithCompression(zip.Deflate)}, opts...)
+	return WriteContext(context.Background(), name, vs, opts...)
+}
+
+// WriteContext writes the values vs to the named npz archive file, like
+// Write.
+// WriteContext checks for the cancellation of the provided context while
+// writing the array data.
+func WriteContext(ctx context.Context, name string, vs map[string]interface{}, opts ...WriteOption) error {
+	w, err := Create(name, opts...)
 	if err != nil {
 		return err
 	}
@@ -31,7 +125,7 @@ func Write(name string, vs map[string]interface{}) error {
 	sort.Strings(ks)
 
 	for _, k := range ks {
-		err = w.Write(k, vs[k])
+		err = w.WriteContext(ctx, k, vs[k])
 		if err != nil {
 			return err
 		}
@@ -50,32 +144,44 @@ type Writer struct {
 	w  io.Writer
 	wz *zip.Writer
 	wc io.Closer
+
+	cfg   writeConfig
+	level int // Deflate compression level of the registered compressor.
+	narrs int // number of arrays written by WriteArrays.
 }
 
 // Create creates the named compressed NumPy data file for writing.
-func Create(name string) (*Writer, error) {
+//
+// The provided options are used to configure how the archive entries
+// are written.
+func Create(name string, opts ...WriteOption) (*Writer, error) {
 	w, err := os.Create(name)
 	if err != nil {
 		return nil, fmt.Errorf("npz: could not create %q: %w", name, err)
 	}
 
-	wz := zip.NewWriter(w)
+	ww := NewWriter(w, opts...)
+	ww.wc = w
 
-	return &Writer{
-		w:  w,
-		wz: wz,
-		wc: w,
-	}, nil
+	return ww, nil
 }
 
 // NewWriter returns a new npz writer.
 //
+// The provided options are used to configure how the archive entries
+// are written.
 // The returned npz writer won't close the underlying writer.
-func NewWriter(w io.Writer) *Writer {
-	return &Writer{
-		w:  w,
-		wz: zip.NewWriter(w),
+func NewWriter(w io.Writer, opts ...WriteOption) *Writer {
+	ww := &Writer{
+		w:     w,
+		wz:    zip.NewWriter(w),
+		cfg:   newWriteConfig(opts),
+		level: flate.DefaultCompression,
+	}
+	for method, comp := range ww.cfg.comps {
+		ww.wz.RegisterCompressor(method, comp)
 	}
+	return ww
 }
 
 // Close closes the npz archive.
@@ -112,16 +218,69 @@ func (w *Writer) Close() error {
 }
 
 // Write writes the named NumPy array data to the npz archive.
-func (w *Writer) Write(name string, v interface{}) error {
-	ww, err := w.wz.Create(name)
+// Like numpy.savez, the ".npy" suffix is appended to the entry name, unless
+// it is already present or WithNpySuffix(false) is used.
+//
+// The provided options override the options of the Writer for this entry.
+func (w *Writer) Write(name string, v interface{}, opts ...WriteOption) error {
+	return w.WriteContext(context.Background(), name, v, opts...)
+}
+
+// WriteContext writes the named NumPy array data to the npz archive, like
+// Write.
+// WriteContext checks for the cancellation of the provided context while
+// writing the array data.
+func (w *Writer) WriteContext(ctx context.Context, name string, v interface{}, opts ...WriteOption) error {
+	cfg := w.cfg
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.method == zip.Deflate {
+		w.setLevel(cfg.level)
+	}
+	if cfg.suffix && !strings.HasSuffix(name, npySuffix) {
+		name += npySuffix
+	}
+
+	ww, err := w.wz.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: cfg.method,
+	})
 	if err != nil {
 		return fmt.Errorf("npz: could not create npz entry %q: %w", name, err)
 	}
 
-	err = npy.Write(ww, v)
+	err = npy.WriteContext(ctx, ww, v)
 	if err != nil {
 		return fmt.Errorf("npz: could not write npz entry %q: %w", name, err)
 	}
 
 	return nil
 }
+
+// WriteArrays writes the provided NumPy array data to the npz archive,
+// under the "arr_0.npy", "arr_1.npy", ... entry names, like numpy.savez
+// does with positional arrays.
+// Successive calls to WriteArrays carry on the numbering of the entries.
+func (w *Writer) WriteArrays(vs ...interface{}) error {
+	for _, v := range vs {
+		err := w.Write(fmt.Sprintf("arr_%d", w.narrs), v)
+		if err != nil {
+			return err
+		}
+		w.narrs++
+	}
+	return nil
+}
+
+// setLevel registers a Deflate compressor with the provided compression
+// level, unless a Deflate compressor was registered with WithCompressor.
+func (w *Writer) setLevel(level int) {
+	if _, ok := w.cfg.comps[zip.Deflate]; ok || level == w.level {
+		return
+	}
+	w.level = level
+	w.wz.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+}
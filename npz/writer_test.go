@@ -5,10 +5,17 @@
 package npz
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/sbinet/npyio/npy"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -99,3 +106,329 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteArray(t *testing.T) {
+	descr, err := npy.ParseDescr(">f4")
+	if err != nil {
+		t.Fatalf("could not parse descr: %+v", err)
+	}
+
+	want, err := npy.NewArray(
+		descr, []int{3, 2}, []float32{0, 1, 2, 3, 4, 5},
+		npy.WithFortranOrder(true),
+	)
+	if err != nil {
+		t.Fatalf("could not create array: %+v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	wz := NewWriter(buf)
+	err = wz.Write("arr.npy", want)
+	if err != nil {
+		t.Fatalf("could not write value: %+v", err)
+	}
+
+	err = wz.Close()
+	if err != nil {
+		t.Fatalf("could not close writer: %+v", err)
+	}
+
+	var got npy.Array
+	err = Read(bytes.NewReader(buf.Bytes()), "arr.npy", &got)
+	if err != nil {
+		t.Fatalf("could not read value: %+v", err)
+	}
+
+	if !reflect.DeepEqual(&got, want) {
+		t.Fatalf("invalid r/w round-trip:\ngot= %v\nwant=%v", &got, want)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buf := new(bytes.Buffer)
+	wz := NewWriter(buf)
+	err := wz.WriteContext(ctx, "data.npy", []float64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("could not write value: %+v", err)
+	}
+
+	cancel()
+	err = wz.WriteContext(ctx, "canceled.npy", []float64{1, 2, 3})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+	}
+
+	err = wz.Close()
+	if err != nil {
+		t.Fatalf("could not close npz archive: %+v", err)
+	}
+
+	rz, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("could not open npz archive: %+v", err)
+	}
+
+	var got []float64
+	err = rz.ReadContext(context.Background(), "data.npy", &got)
+	if err != nil {
+		t.Fatalf("could not read value: %+v", err)
+	}
+	if want := []float64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid value:\ngot= %v\nwant=%v", got, want)
+	}
+
+	err = rz.ReadContext(ctx, "data.npy", &got)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+	}
+}
+
+func TestWriteCompression(t *testing.T) {
+	data := make([]float64, 1024)
+	raw := new(bytes.Buffer)
+	err := npy.Write(raw, data)
+	if err != nil {
+		t.Fatalf("could not write npy data: %+v", err)
+	}
+
+	entries := func(t *testing.T, raw []byte) map[string]*zip.File {
+		t.Helper()
+		rz, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			t.Fatalf("could not open zip archive: %+v", err)
+		}
+		files := make(map[string]*zip.File)
+		for _, f := range rz.File {
+			files[f.Name] = f
+		}
+		return files
+	}
+
+	t.Run("writer", func(t *testing.T) {
+		var ncomps int
+		for _, tc := range []struct {
+			name   string
+			opts   []WriteOption
+			method uint16
+		}{
+			{"default", nil, zip.Deflate},
+			{"deflate", []WriteOption{WithCompression(zip.Deflate)}, zip.Deflate},
+			{"store", []WriteOption{WithCompression(zip.Store)}, zip.Store},
+			{"no-compression", []WriteOption{WithCompressionLevel(flate.NoCompression)}, zip.Deflate},
+			{"best-speed", []WriteOption{WithCompressionLevel(flate.BestSpeed)}, zip.Deflate},
+			{"compressor", []WriteOption{WithCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+				ncomps++
+				return flate.NewWriter(w, flate.BestCompression)
+			})}, zip.Deflate},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				buf := new(bytes.Buffer)
+				wz := NewWriter(buf, tc.opts...)
+				err := wz.Write("data.npy", data)
+				if err != nil {
+					t.Fatalf("could not write entry: %+v", err)
+				}
+				err = wz.Close()
+				if err != nil {
+					t.Fatalf("could not close archive: %+v", err)
+				}
+
+				f := entries(t, buf.Bytes())["data.npy"]
+				if got, want := f.Method, tc.method; got != want {
+					t.Fatalf("invalid compression method:\ngot= %d\nwant=%d", got, want)
+				}
+				switch tc.name {
+				case "store":
+					off, err := f.DataOffset()
+					if err != nil {
+						t.Fatalf("could not retrieve data offset: %+v", err)
+					}
+					got := buf.Bytes()[off : off+int64(raw.Len())]
+					if !bytes.Equal(got, raw.Bytes()) {
+						t.Fatalf("stored entry is not contiguous")
+					}
+				case "no-compression":
+					if f.CompressedSize64 < f.UncompressedSize64 {
+						t.Fatalf("entry was compressed: %d < %d bytes", f.CompressedSize64, f.UncompressedSize64)
+					}
+				default:
+					if f.CompressedSize64 >= f.UncompressedSize64 {
+						t.Fatalf("entry was not compressed: %d >= %d bytes", f.CompressedSize64, f.UncompressedSize64)
+					}
+				}
+
+				var got []float64
+				err = Read(bytes.NewReader(buf.Bytes()), "data.npy", &got)
+				if err != nil {
+					t.Fatalf("could not read entry: %+v", err)
+				}
+				if !reflect.DeepEqual(got, data) {
+					t.Fatalf("invalid r/w round-trip")
+				}
+			})
+		}
+		if ncomps != 1 {
+			t.Fatalf("invalid number of compressor calls: got=%d, want=1", ncomps)
+		}
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		wz := NewWriter(buf, WithCompression(zip.Store))
+		for _, e := range []struct {
+			name string
+			opts []WriteOption
+		}{
+			{"stored.npy", nil},
+			{"deflated.npy", []WriteOption{WithCompression(zip.Deflate)}},
+			{"no-compression.npy", []WriteOption{WithCompression(zip.Deflate), WithCompressionLevel(flate.NoCompression)}},
+			{"deflated-default.npy", []WriteOption{WithCompression(zip.Deflate)}},
+		} {
+			err := wz.Write(e.name, data, e.opts...)
+			if err != nil {
+				t.Fatalf("could not write entry %q: %+v", e.name, err)
+			}
+		}
+		err := wz.Close()
+		if err != nil {
+			t.Fatalf("could not close archive: %+v", err)
+		}
+
+		files := entries(t, buf.Bytes())
+		for _, tc := range []struct {
+			name       string
+			method     uint16
+			compressed bool
+		}{
+			{"stored.npy", zip.Store, false},
+			{"deflated.npy", zip.Deflate, true},
+			{"no-compression.npy", zip.Deflate, false},
+			{"deflated-default.npy", zip.Deflate, true},
+		} {
+			f := files[tc.name]
+			if got, want := f.Method, tc.method; got != want {
+				t.Fatalf("invalid compression method for %q:\ngot= %d\nwant=%d", tc.name, got, want)
+			}
+			if got, want := f.CompressedSize64 < f.UncompressedSize64, tc.compressed; got != want {
+				t.Fatalf("invalid compression for %q:\ngot= %v\nwant=%v", tc.name, got, want)
+			}
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		dir := t.TempDir()
+		vs := map[string]interface{}{"data.npy": data}
+		for _, tc := range []struct {
+			name   string
+			write  func(name string, vs map[string]interface{}, opts ...WriteOption) error
+			opts   []WriteOption
+			method uint16
+		}{
+			{"write", Write, nil, zip.Deflate},
+			{"write-store", Write, []WriteOption{WithCompression(zip.Store)}, zip.Store},
+			{"write-compressed", WriteCompressed, nil, zip.Deflate},
+			{"write-compressed-store", WriteCompressed, []WriteOption{WithCompression(zip.Store)}, zip.Store},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				fname := filepath.Join(dir, tc.name+".npz")
+				err := tc.write(fname, vs, tc.opts...)
+				if err != nil {
+					t.Fatalf("could not write archive: %+v", err)
+				}
+
+				rz, err := zip.OpenReader(fname)
+				if err != nil {
+					t.Fatalf("could not open archive: %+v", err)
+				}
+				defer rz.Close()
+
+				if got, want := rz.File[0].Method, tc.method; got != want {
+					t.Fatalf("invalid compression method:\ngot= %d\nwant=%d", got, want)
+				}
+			})
+		}
+	})
+}
+
+func TestWriteNames(t *testing.T) {
+	buf := new(bytes.Buffer)
+	wz := NewWriter(buf)
+	for _, e := range []struct {
+		name string
+		opts []WriteOption
+	}{
+		{"x", nil},
+		{"y.npy", nil},
+		{"raw", []WriteOption{WithNpySuffix(false)}},
+	} {
+		err := wz.Write(e.name, []int32{1, 2}, e.opts...)
+		if err != nil {
+			t.Fatalf("could not write %q: %+v", e.name, err)
+		}
+	}
+	err := wz.WriteArrays([]int32{3}, []int32{4})
+	if err != nil {
+		t.Fatalf("could not write arrays: %+v", err)
+	}
+	err = wz.WriteArrays([]int32{5})
+	if err != nil {
+		t.Fatalf("could not write arrays: %+v", err)
+	}
+	err = wz.Close()
+	if err != nil {
+		t.Fatalf("could not close archive: %+v", err)
+	}
+
+	raw := buf.Bytes()
+	rz, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("could not open zip archive: %+v", err)
+	}
+	var names []string
+	for _, f := range rz.File {
+		names = append(names, f.Name)
+	}
+	if got, want := names, []string{"x.npy", "y.npy", "raw", "arr_0.npy", "arr_1.npy", "arr_2.npy"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid entry names:\ngot= %q\nwant=%q", got, want)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("could not open npz archive: %+v", err)
+	}
+	if got, want := r.Keys(), []string{"x", "y", "raw", "arr_0", "arr_1", "arr_2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid keys:\ngot= %q\nwant=%q", got, want)
+	}
+
+	for _, tc := range []struct {
+		name string
+		want []int32
+	}{
+		{"x", []int32{1, 2}},
+		{"x.npy", []int32{1, 2}},
+		{"y", []int32{1, 2}},
+		{"raw", []int32{1, 2}},
+		{"arr_1", []int32{4}},
+		{"arr_2.npy", []int32{5}},
+	} {
+		var got []int32
+		err := r.Read(tc.name, &got)
+		if err != nil {
+			t.Fatalf("could not read %q: %+v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("invalid %q data:\ngot= %v\nwant=%v", tc.name, got, tc.want)
+		}
+		if hdr := r.Header(tc.name); hdr == nil {
+			t.Fatalf("could not retrieve %q header", tc.name)
+		}
+	}
+
+	err = r.Read("raw.npy", new([]int32))
+	if err == nil {
+		t.Fatalf("expected an error reading a missing entry")
+	}
+}